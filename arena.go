@@ -0,0 +1,74 @@
+package ber
+
+import "bytes"
+
+// PacketArena pre-allocates Packet structs for DecodePacketArena, reducing
+// per-packet allocations when decoding at high rates. Packets handed out by an
+// arena are only valid until the next call to Reset; the caller is responsible
+// for resetting the arena between batches once the decoded trees are no longer
+// referenced. A PacketArena is not safe for concurrent use.
+type PacketArena struct {
+	packets []Packet
+	next    int
+}
+
+// NewPacketArena returns an arena holding size pre-allocated packets. Once
+// exhausted, the arena falls back to regular heap allocation.
+func NewPacketArena(size int) *PacketArena {
+	return &PacketArena{packets: make([]Packet, size)}
+}
+
+// Reset makes every packet of the arena available again. Trees previously
+// decoded from the arena must not be used afterwards.
+func (a *PacketArena) Reset() {
+	a.next = 0
+}
+
+// Len returns the number of arena packets currently in use.
+func (a *PacketArena) Len() int {
+	return a.next
+}
+
+func (a *PacketArena) alloc(identifier Identifier) *Packet {
+	if a.next >= len(a.packets) {
+		return &Packet{
+			Identifier: identifier,
+			Data:       new(bytes.Buffer),
+			Children:   make([]*Packet, 0, 2),
+		}
+	}
+
+	p := &a.packets[a.next]
+	a.next++
+
+	// Keep the buffers allocated by a previous batch, but drop everything else
+	data, children := p.Data, p.Children
+	if data == nil {
+		data = new(bytes.Buffer)
+	}
+	data.Reset()
+	if children == nil {
+		children = make([]*Packet, 0, 2)
+	}
+	for i := range children {
+		children[i] = nil
+	}
+
+	*p = Packet{
+		Identifier: identifier,
+		Data:       data,
+		Children:   children[:0],
+	}
+	return p
+}
+
+// DecodePacketArena decodes the given bytes into a single Packet, drawing the
+// packet structs from arena.
+func DecodePacketArena(data []byte, arena *PacketArena) (*Packet, error) {
+	d := &decoder{arena: arena}
+	p, _, err := d.readPacket(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
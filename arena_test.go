@@ -0,0 +1,65 @@
+package ber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func arenaTestSequence(n int) []byte {
+	sequence := NewSequence("")
+	for i := 0; i < n; i++ {
+		sequence.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, int64(i), ""))
+	}
+	return sequence.Bytes()
+}
+
+func TestDecodePacketArena(t *testing.T) {
+	arena := NewPacketArena(8)
+
+	for round, n := range []int{5, 3, 20} {
+		data := arenaTestSequence(n)
+
+		p, err := DecodePacketArena(data, arena)
+		if err != nil {
+			t.Fatalf("round %d: unexpected error: %v", round, err)
+		}
+		if len(p.Children) != n {
+			t.Fatalf("round %d: expected %d children, got %d", round, n, len(p.Children))
+		}
+		for i, child := range p.Children {
+			if v, ok := child.Value.(int64); !ok || v != int64(i) {
+				t.Errorf("round %d: child %d: expected %d, got %v", round, i, i, child.Value)
+			}
+		}
+		if !bytes.Equal(data, p.Bytes()) {
+			t.Errorf("round %d: re-encoding differs\nwant: % X\ngot:  % X", round, data, p.Bytes())
+		}
+
+		arena.Reset()
+		if arena.Len() != 0 {
+			t.Errorf("round %d: expected empty arena after reset, got %d", round, arena.Len())
+		}
+	}
+}
+
+func BenchmarkDecodePacket(b *testing.B) {
+	data := arenaTestSequence(100)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodePacketErr(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodePacketArena(b *testing.B) {
+	data := arenaTestSequence(100)
+	arena := NewPacketArena(128)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodePacketArena(data, arena); err != nil {
+			b.Fatal(err)
+		}
+		arena.Reset()
+	}
+}
@@ -7,7 +7,8 @@ import (
 	"io"
 	"io/ioutil"
 	"math"
-	"os"
+	"math/big"
+	"net"
 	"reflect"
 	"strconv"
 	"strings"
@@ -19,6 +20,34 @@ import (
 // no limit.
 var MaxPacketLengthBytes int64 = math.MaxInt32
 
+// MaxPacketDepth specifies the maximum nesting depth of constructed packets when calling ReadPacket or DecodePacket,
+// protecting the stack against maliciously nested input. Set to 0 for no limit.
+var MaxPacketDepth = 1000
+
+// ErrMaxDepthExceeded is returned when constructed packets are nested deeper than MaxPacketDepth.
+var ErrMaxDepthExceeded = errors.New("maximum packet depth exceeded")
+
+// DecodeError is returned by the decode functions, e.g. ReadPacket and
+// DecodePacketErr, when decoding fails, including on truncated input. Its
+// message is that of the cause, which can be checked with errors.Is, e.g.
+// errors.Is(err, io.ErrUnexpectedEOF). Reaching the end of the input before a
+// packet started is reported as a plain io.EOF, and ErrMaxDepthExceeded and
+// ErrDecodeTimeout are returned as they are.
+type DecodeError struct {
+	// Offset of the first identifier octet of the innermost packet that failed
+	// to decode, relative to the first byte read by the failing call
+	Offset int
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
 type Packet struct {
 	Identifier
 	Value       interface{}
@@ -26,6 +55,14 @@ type Packet struct {
 	Data        *bytes.Buffer
 	Children    []*Packet
 	Description string
+	// IndefiniteLength makes Bytes encode a constructed packet with indefinite
+	// length, terminated by an end-of-contents marker. It must be set before
+	// the packet is appended to its parent. Decoding does not set it, see
+	// UsedIndefiniteLength.
+	IndefiniteLength bool
+
+	// indefinite is set when the packet was decoded from an indefinite-length encoding
+	indefinite bool
 }
 
 type Identifier struct {
@@ -170,7 +207,16 @@ func WritePacket(out io.Writer, p *Packet) {
 }
 
 func PrintPacket(p *Packet) {
-	printPacket(os.Stdout, p, 0, false)
+	fmt.Print(p.String())
+}
+
+// String returns the indented tree of p and its children as printed by
+// PrintPacket, one line per packet holding its class, type, tag, description
+// and decoded Value.
+func (p *Packet) String() string {
+	var s strings.Builder
+	printPacket(&s, p, 0, false)
+	return s.String()
 }
 
 // Return a string describing packet content. This is not recursive,
@@ -225,6 +271,46 @@ func ReadPacket(reader io.Reader) (*Packet, error) {
 	return p, nil
 }
 
+// ReadPacketLimit reads a single Packet from the reader, failing as soon as a
+// declared length or the bytes read so far exceed maxLen, before reading or
+// allocating the content. This protects against peers announcing huge
+// lengths, also for indefinite-length packets.
+func ReadPacketLimit(reader io.Reader, maxLen int) (*Packet, error) {
+	d := &decoder{maxSize: maxLen}
+	p, _, err := d.readPacket(reader)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ReadPacketMulti reads a single Packet from the concatenation of readers, for
+// packets that are fragmented across several transports. A packet may span
+// any number of readers; readers are consumed in order and only as far as
+// needed to complete the packet.
+func ReadPacketMulti(readers ...io.Reader) (*Packet, error) {
+	return ReadPacket(io.MultiReader(readers...))
+}
+
+// ReadAllPackets reads back-to-back packets from reader until it is
+// exhausted. A stream ending between packets is a clean termination and
+// returns a nil error. Otherwise the packets read so far are returned together
+// with the error, which matches io.ErrUnexpectedEOF if the last packet is
+// truncated.
+func ReadAllPackets(reader io.Reader) ([]*Packet, error) {
+	var packets []*Packet
+	for {
+		p, err := ReadPacket(reader)
+		if err == io.EOF {
+			return packets, nil
+		}
+		if err != nil {
+			return packets, err
+		}
+		packets = append(packets, p)
+	}
+}
+
 func DecodeString(data []byte) string {
 	return string(data)
 }
@@ -246,39 +332,10 @@ func ParseInt64(bytes []byte) (ret int64, err error) {
 	return
 }
 
-func encodeInteger(i int64) []byte {
-	n := int64Length(i)
-	out := make([]byte, n)
-
-	var j int
-	for ; n > 0; n-- {
-		out[j] = byte(i >> uint((n-1)*8))
-		j++
-	}
-
-	return out
-}
-
-func int64Length(i int64) (numBytes int) {
-	numBytes = 1
-
-	for i > 127 {
-		numBytes++
-		i >>= 8
-	}
-
-	for i < -128 {
-		numBytes++
-		i >>= 8
-	}
-
-	return
-}
-
 // DecodePacket decodes the given bytes into a single Packet
 // If a decode error is encountered, nil is returned.
 func DecodePacket(data []byte) *Packet {
-	p, _, _ := readPacket(bytes.NewBuffer(data))
+	p, _ := DecodePacketErr(data)
 
 	return p
 }
@@ -293,24 +350,175 @@ func DecodePacketErr(data []byte) (*Packet, error) {
 	return p, nil
 }
 
+// DecodeExact decodes the given bytes into a single Packet like
+// DecodePacketErr, but returns an error if data holds anything beyond that
+// packet, e.g. because two packets were concatenated.
+func DecodeExact(data []byte) (*Packet, error) {
+	buf := bytes.NewBuffer(data)
+	p, _, err := readPacket(buf)
+	if err != nil {
+		return nil, err
+	}
+	if buf.Len() > 0 {
+		return nil, fmt.Errorf("trailing data after packet: %d bytes", buf.Len())
+	}
+	return p, nil
+}
+
+// DecodePacketString decodes the raw bytes held by s into a single Packet
+// If a decode error is encountered, nil is returned.
+func DecodePacketString(s string) *Packet {
+	p, _, _ := readPacket(strings.NewReader(s))
+
+	return p
+}
+
+// ReadPacketString reads a single Packet from the raw bytes held by s.
+func ReadPacketString(s string) (*Packet, error) {
+	return ReadPacket(strings.NewReader(s))
+}
+
+// decoder holds the options threaded through the decoding of a single packet tree.
+type decoder struct {
+	opts     DecodeOptions
+	arena    *PacketArena
+	deadline time.Time
+	// maxSize limits the total number of bytes of the packet, size counts
+	// the bytes read so far
+	maxSize, size int
+	// depth is the number of constructed packets currently being read
+	depth int
+	// allocated counts the bytes held by the tree for MaxTotalAllocation
+	allocated int
+	// offset counts the bytes read so far, for DecodeError
+	offset int
+	// inString is set while reading the fragments of a constructed string,
+	// whose Value is only computed for the outermost one
+	inString bool
+	// stringLength sums the fragments of the constructed string being read,
+	// for MaxStringLength
+	stringLength int
+}
+
+// allocate accounts for n more bytes held by the decoded tree, failing if
+// that exceeds opts.MaxTotalAllocation.
+func (d *decoder) allocate(n int) error {
+	if d.opts.MaxTotalAllocation <= 0 {
+		return nil
+	}
+	if n > d.opts.MaxTotalAllocation-d.allocated {
+		return fmt.Errorf("total allocation exceeds maximum %d", d.opts.MaxTotalAllocation)
+	}
+	d.allocated += n
+	return nil
+}
+
 // readPacket reads a single Packet from the reader, returning the number of bytes read.
 func readPacket(reader io.Reader) (*Packet, int, error) {
+	return (&decoder{}).readPacket(reader)
+}
+
+// newPacket returns an empty packet for the given identifier, drawn from the arena if one is set.
+func (d *decoder) newPacket(identifier Identifier, length int) *Packet {
+	if d.arena != nil {
+		return d.arena.alloc(identifier)
+	}
+	return &Packet{
+		Identifier: identifier,
+		Data:       new(bytes.Buffer),
+		Children:   make([]*Packet, 0, childrenCapacity(identifier, length)),
+	}
+}
+
+// childrenCapacity estimates the number of children of a constructed value
+// from its content length, assuming every child is a minimal TLV. The declared
+// length isn't backed by any input yet, so the estimate is capped at a small
+// capacity and larger packets grow Children by appending. Primitive values
+// never have children, so no capacity is reserved for them.
+func childrenCapacity(identifier Identifier, length int) int {
+	const (
+		minTLVSize  = 2
+		maxCapacity = 16
+	)
+	if identifier.TagType != TypeConstructed {
+		return 0
+	}
+	if length == LengthIndefinite {
+		return 2
+	}
+	n := length / minTLVSize
+	if n > maxCapacity {
+		return maxCapacity
+	}
+	return n
+}
+
+// partial returns the partially decoded packet p when decoding fails in
+// best-effort mode, and nil otherwise.
+func (d *decoder) partial(p *Packet) *Packet {
+	if d.opts.BestEffort {
+		return p
+	}
+	return nil
+}
+
+// readPacket reads a single Packet using readElement, reporting all errors but
+// the sentinel errors as a DecodeError holding the offset of the innermost
+// packet that failed.
+func (d *decoder) readPacket(reader io.Reader) (*Packet, int, error) {
+	start := d.offset
+	p, read, err := d.readElement(reader)
+	d.offset = start + read
+	switch err.(type) {
+	case nil, *DecodeError:
+		return p, read, err
+	}
+	switch err {
+	case io.EOF, ErrMaxDepthExceeded, ErrDecodeTimeout:
+		return p, read, err
+	}
+	return p, read, &DecodeError{Offset: start, Err: err}
+}
+
+func (d *decoder) readElement(reader io.Reader) (*Packet, int, error) {
+	if !d.deadline.IsZero() && time.Now().After(d.deadline) {
+		return nil, 0, ErrDecodeTimeout
+	}
+
 	identifier, length, read, err := readHeader(reader)
 	if err != nil {
 		return nil, read, err
 	}
-
-	p := &Packet{
-		Identifier: identifier,
+	d.offset += read
+
+	// Enforce the size limit on the declared length before allocating anything,
+	// and on the bytes read so far for children of indefinite-length packets
+	if d.maxSize > 0 {
+		d.size += read
+		if d.size > d.maxSize || (length != LengthIndefinite && length > d.maxSize-d.size) {
+			return nil, read, fmt.Errorf("packet size exceeds maximum %d", d.maxSize)
+		}
 	}
 
-	p.Data = new(bytes.Buffer)
-	p.Children = make([]*Packet, 0, 2)
-	p.Value = nil
+	p := d.newPacket(identifier, length)
+	p.indefinite = length == LengthIndefinite
 
 	if p.TagType == TypeConstructed {
 		// TODO: if universal, ensure tag type is allowed to be constructed
 
+		d.depth++
+		defer func() { d.depth-- }()
+		if MaxPacketDepth > 0 && d.depth > MaxPacketDepth {
+			return nil, read, ErrMaxDepthExceeded
+		}
+
+		outermostString := !d.inString && p.ClassType == ClassUniversal && isStringTag(p.Tag)
+		if outermostString {
+			d.inString = true
+			d.stringLength = 0
+			defer func() { d.inString = false }()
+		}
+
 		// Track how much content we've read
 		contentRead := 0
 		for {
@@ -321,14 +529,21 @@ func readPacket(reader io.Reader) (*Packet, int, error) {
 				}
 				// Detect if a packet boundary didn't fall on the expected length
 				if contentRead > length {
-					return nil, read, fmt.Errorf("expected to read %d bytes, read %d", length, contentRead)
+					return d.partial(p), read, fmt.Errorf("expected to read %d bytes, read %d", length, contentRead)
 				}
 			}
 
 			// Read the next packet
-			child, r, err := readPacket(reader)
+			childStart := d.offset
+			child, r, err := d.readPacket(reader)
 			if err != nil {
-				return nil, read, unexpectedEOF(err)
+				if d.opts.BestEffort && child != nil {
+					p.AppendChild(child)
+				}
+				if err == io.EOF {
+					err = &DecodeError{Offset: childStart, Err: io.ErrUnexpectedEOF}
+				}
+				return d.partial(p), read, err
 			}
 			contentRead += r
 			read += r
@@ -338,12 +553,23 @@ func readPacket(reader io.Reader) (*Packet, int, error) {
 				if length == LengthIndefinite {
 					break
 				}
-				return nil, read, errors.New("eoc child not allowed with definite length")
+				return d.partial(p), read, errors.New("eoc child not allowed with definite length")
 			}
 
-			// Append and continue
+			// Append and continue, the child's encoding is copied into our Data
+			if err := d.allocate(r); err != nil {
+				return d.partial(p), read, err
+			}
 			p.AppendChild(child)
 		}
+
+		// Constructed strings get the Value of their concatenated fragments
+		// (x.690, 8.23.6), computed once for the outermost string.
+		if outermostString {
+			if err := d.reassembleString(p); err != nil {
+				return d.partial(p), read, err
+			}
+		}
 		return p, read, nil
 	}
 
@@ -356,6 +582,26 @@ func readPacket(reader io.Reader) (*Packet, int, error) {
 		return nil, read, fmt.Errorf("length %d greater than maximum %d", length, MaxPacketLengthBytes)
 	}
 
+	if d.opts.MaxStringLength > 0 && p.ClassType == ClassUniversal && isStringTag(p.Tag) {
+		total := length
+		if d.inString {
+			d.stringLength += length
+			total = d.stringLength
+		}
+		if total > d.opts.MaxStringLength {
+			return nil, read, fmt.Errorf("string length %d greater than maximum %d", total, d.opts.MaxStringLength)
+		}
+	}
+
+	if err := d.allocate(length); err != nil {
+		return nil, read, err
+	}
+
+	// When decoding from memory, reject a length exceeding the available bytes up front
+	if buf, ok := reader.(interface{ Len() int }); ok && length > buf.Len() {
+		return nil, read, io.ErrUnexpectedEOF
+	}
+
 	var content []byte
 	if length > 0 {
 		// Read the content and limit it to the parsed length.
@@ -368,6 +614,7 @@ func readPacket(reader io.Reader) (*Packet, int, error) {
 			return nil, read, unexpectedEOF(err)
 		}
 		read += len(content)
+		d.size += len(content)
 	} else {
 		// If length == 0, we set the ByteValue to an empty slice
 		content = make([]byte, 0)
@@ -376,82 +623,124 @@ func readPacket(reader io.Reader) (*Packet, int, error) {
 	if p.ClassType == ClassUniversal {
 		p.Data.Write(content)
 		p.ByteValue = content
+		err = d.decodeUniversalValue(p, content)
+	} else {
+		p.Data.Write(content)
+		if p.ClassType == ClassApplication {
+			err = decodeApplicationValue(p, content, d.opts.ApplicationDecoders)
+		}
+	}
+
+	return p, read, err
+}
 
-		switch p.Tag {
-		case TagEOC:
-		case TagBoolean:
+// decodeUniversalValue sets the Value of the universal primitive packet p from its content.
+func (d *decoder) decodeUniversalValue(p *Packet, content []byte) (err error) {
+	switch p.Tag {
+	case TagEOC:
+	case TagBoolean:
+		if d.opts.Strict {
+			err = validateBooleanDER(content)
+		}
+		if err == nil {
 			val, _ := ParseInt64(content)
 
 			p.Value = val != 0
-		case TagInteger:
-			p.Value, _ = ParseInt64(content)
-		case TagBitString:
-		case TagOctetString:
-			// the actual string encoding is not known here
-			// (e.g. for LDAP content is already an UTF8-encoded
-			// string). Return the data without further processing
-			p.Value = DecodeString(content)
-		case TagNULL:
-		case TagObjectIdentifier:
-			oid, err := parseObjectIdentifier(content)
-			if err == nil {
-				p.Value = OIDToString(oid)
+		}
+	case TagInteger:
+		if d.opts.Strict {
+			err = validateIntegerDER(content)
+		}
+		if err == nil {
+			p.Value = parseIntegerValue(content)
+		}
+	case TagBitString:
+		if b, err := ParseBitString(content); err == nil {
+			p.Value = b
+		}
+	case TagOctetString:
+		// the actual string encoding is not known here
+		// (e.g. for LDAP content is already an UTF8-encoded
+		// string). Return the data without further processing
+		p.Value = DecodeString(content)
+	case TagNULL:
+	case TagObjectIdentifier:
+		// Arcs too large for an int, e.g. UUIDs below 2.25, are parsed with big.Int
+		if oid, err := parseObjectIdentifier(content); err == nil {
+			p.Value = OIDToString(oid)
+		} else if err == errBase128TooLarge {
+			if oid, err := parseObjectIdentifierBig(content); err == nil {
+				p.Value = bigOIDToString(oid)
 			}
-		case TagObjectDescriptor:
-		case TagExternal:
-		case TagRealFloat:
-			p.Value, err = ParseReal(content)
-		case TagEnumerated:
+		}
+	case TagObjectDescriptor:
+	case TagExternal:
+	case TagRealFloat:
+		p.Value, err = ParseReal(content)
+	case TagEnumerated:
+		if d.opts.Strict {
+			p.Value, err = ParseInt64Strict(content)
+		} else {
 			p.Value, _ = ParseInt64(content)
-		case TagEmbeddedPDV:
-		case TagUTF8String:
-			val := DecodeString(content)
-			if !utf8.Valid([]byte(val)) {
-				err = errors.New("invalid UTF-8 string")
-			} else {
-				p.Value = val
-			}
-		case TagRelativeOID:
-			oid, err := parseRelativeObjectIdentifier(content)
-			if err == nil {
-				p.Value = OIDToString(oid)
-			}
-		case TagSequence:
-		case TagSet:
-		case TagNumericString:
-		case TagPrintableString:
-			val := DecodeString(content)
-			if err = isPrintableString(val); err == nil {
-				p.Value = val
-			}
-		case TagT61String:
-		case TagVideotexString:
-		case TagIA5String:
-			val := DecodeString(content)
-			for i, c := range val {
-				if c >= 0x7F {
-					err = fmt.Errorf("invalid character for IA5String at pos %d: %c", i, c)
-					break
-				}
-			}
-			if err == nil {
-				p.Value = val
+		}
+	case TagEmbeddedPDV:
+	case TagUTF8String:
+		val := DecodeString(content)
+		if !utf8.Valid([]byte(val)) {
+			err = errors.New("invalid UTF-8 string")
+		} else {
+			p.Value = val
+		}
+	case TagRelativeOID:
+		oid, err := parseRelativeObjectIdentifier(content)
+		if err == nil {
+			p.Value = oid
+		}
+	case TagSequence:
+	case TagSet:
+	case TagNumericString:
+	case TagPrintableString:
+		val := DecodeString(content)
+		if err = isPrintableString(val); err == nil {
+			p.Value = val
+		}
+	case TagT61String:
+	case TagVideotexString:
+	case TagIA5String:
+		val := DecodeString(content)
+		for i, c := range val {
+			if c >= 0x7F {
+				err = fmt.Errorf("invalid character for IA5String at pos %d: %c", i, c)
+				break
 			}
-		case TagUTCTime:
-		case TagGeneralizedTime:
-			p.Value, err = ParseGeneralizedTime(content)
-		case TagGraphicString:
-		case TagVisibleString:
-		case TagGeneralString:
-		case TagUniversalString:
-		case TagCharacterString:
-		case TagBMPString:
 		}
-	} else {
-		p.Data.Write(content)
+		if err == nil {
+			p.Value = val
+		}
+	case TagUTCTime:
+		p.Value, err = ParseUTCTime(content)
+	case TagGeneralizedTime:
+		p.Value, err = ParseGeneralizedTime(content)
+	case TagGraphicString:
+	case TagVisibleString:
+	case TagGeneralString:
+	case TagUniversalString:
+	case TagCharacterString:
+	case TagBMPString:
 	}
+	return err
+}
 
-	return p, read, err
+// validateBooleanDER checks that a BOOLEAN consists of a single octet (x.690,
+// 8.2.1) which is 0xFF for true (x.690, 11.1).
+func validateBooleanDER(content []byte) error {
+	if len(content) != 1 {
+		return fmt.Errorf("BOOLEAN must consist of a single octet, got %d", len(content))
+	}
+	if content[0] != 0x00 && content[0] != 0xFF {
+		return fmt.Errorf("BOOLEAN true must be encoded as 0xFF, got 0x%02X", content[0])
+	}
+	return nil
 }
 
 func isPrintableString(val string) error {
@@ -475,17 +764,304 @@ func (p *Packet) Bytes() []byte {
 	var out bytes.Buffer
 
 	out.Write(encodeIdentifier(p.Identifier))
+	if p.encodesIndefinite() {
+		out.WriteByte(LengthLongFormBitmask)
+		out.Write(p.Data.Bytes())
+		out.Write([]byte{0x00, 0x00})
+		return out.Bytes()
+	}
 	out.Write(encodeLength(p.Data.Len()))
 	out.Write(p.Data.Bytes())
 
 	return out.Bytes()
 }
 
+// encodesIndefinite reports whether Bytes uses the indefinite length form,
+// which is only allowed for constructed packets (x.690, 8.1.3.2).
+func (p *Packet) encodesIndefinite() bool {
+	return p.IndefiniteLength && p.TagType == TypeConstructed
+}
+
+// Size returns the number of bytes Bytes produces, i.e. the identifier and
+// length octets and the content, without encoding the packet. It is the same
+// as ByteLen.
+func (p *Packet) Size() int {
+	return p.ByteLen()
+}
+
+// ByteLen returns the number of bytes Bytes would produce, i.e. the identifier
+// and length octets and the content, without encoding the packet. The content
+// of constructed packets holds the encodings of their children, so it is not
+// recomputed.
+func (p *Packet) ByteLen() int {
+	if p.encodesIndefinite() {
+		return identifierLength(p.Identifier) + 1 + p.Data.Len() + 2
+	}
+	return identifierLength(p.Identifier) + lengthLength(p.Data.Len()) + p.Data.Len()
+}
+
+// EncodeAt writes the encoding of p into dst starting at offset and returns
+// the offset following it. An error is returned, leaving dst untouched, if dst
+// is too small to hold the encoding.
+func (p *Packet) EncodeAt(dst []byte, offset int) (int, error) {
+	n := p.ByteLen()
+	if offset < 0 || offset > len(dst) || len(dst)-offset < n {
+		return offset, fmt.Errorf("encoded packet length %d exceeds the %d bytes available at offset %d", n, len(dst)-offset, offset)
+	}
+
+	end := offset + copy(dst[offset:], encodeIdentifier(p.Identifier))
+	if p.encodesIndefinite() {
+		dst[end] = LengthLongFormBitmask
+		end++
+		end += copy(dst[end:], p.Data.Bytes())
+		end += copy(dst[end:], []byte{0x00, 0x00})
+		return end, nil
+	}
+	end += copy(dst[end:], encodeLength(p.Data.Len()))
+	end += copy(dst[end:], p.Data.Bytes())
+	return end, nil
+}
+
+// WriteTo implements io.WriterTo by writing the encoding of p to w, without
+// materializing it like Bytes does. Constructed packets already hold the
+// encodings of their children in Data, which is written as is, so Size gives
+// the number of bytes written up front.
+func (p *Packet) WriteTo(w io.Writer) (int64, error) {
+	header := encodeIdentifier(p.Identifier)
+	if p.encodesIndefinite() {
+		header = append(header, LengthLongFormBitmask)
+	} else {
+		header = append(header, encodeLength(p.Data.Len())...)
+	}
+
+	n, err := w.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+	n, err = w.Write(p.Data.Bytes())
+	written += int64(n)
+	if err != nil || !p.encodesIndefinite() {
+		return written, err
+	}
+	n, err = w.Write([]byte{0x00, 0x00})
+	return written + int64(n), err
+}
+
+// BytesWithLimit returns the encoded packet, or an error without encoding it
+// if the encoding would be larger than max bytes.
+func (p *Packet) BytesWithLimit(max int) ([]byte, error) {
+	if n := p.ByteLen(); n > max {
+		return nil, fmt.Errorf("encoded packet length %d exceeds limit %d", n, max)
+	}
+	return p.Bytes(), nil
+}
+
+// EncodeFiltered re-encodes the tree, omitting every node for which keep
+// returns false together with its children. Lengths of the enclosing
+// constructed packets are adjusted accordingly, packets using the indefinite
+// length form keep it as in Bytes. If the root itself is not kept, nil is
+// returned.
+func (p *Packet) EncodeFiltered(keep func(*Packet) bool) []byte {
+	if !keep(p) {
+		return nil
+	}
+	if len(p.Children) == 0 {
+		return p.Bytes()
+	}
+
+	var content bytes.Buffer
+	for _, child := range p.Children {
+		content.Write(child.EncodeFiltered(keep))
+	}
+
+	var out bytes.Buffer
+	out.Write(encodeIdentifier(p.Identifier))
+	if p.encodesIndefinite() {
+		out.WriteByte(LengthLongFormBitmask)
+		out.Write(content.Bytes())
+		out.Write([]byte{0x00, 0x00})
+		return out.Bytes()
+	}
+	out.Write(encodeLength(content.Len()))
+	out.Write(content.Bytes())
+
+	return out.Bytes()
+}
+
+// AppendChild appends child to the packet's children and encoded data. It
+// panics if child is nil.
 func (p *Packet) AppendChild(child *Packet) {
+	if child == nil {
+		panic("ber: AppendChild called with a nil child")
+	}
 	p.Data.Write(child.Bytes())
 	p.Children = append(p.Children, child)
 }
 
+// ReinterpretChild re-decodes the content of the primitive child at index as
+// the universal type tag, updating its Value in place. This is useful for
+// fields typed only by context, e.g. a [0] tagged blob holding an INTEGER. The
+// child's identifier and encoding are left unchanged.
+func (p *Packet) ReinterpretChild(index int, tag Tag) error {
+	if index < 0 || index >= len(p.Children) {
+		return fmt.Errorf("child index %d out of range, packet has %d children", index, len(p.Children))
+	}
+	child := p.Children[index]
+	if child.TagType != TypePrimitive {
+		return errors.New("only primitive children can be reinterpreted")
+	}
+
+	content := child.Data.Bytes()
+	tmp := &Packet{Identifier: Identifier{ClassType: ClassUniversal, TagType: TypePrimitive, Tag: tag}}
+	if err := (&decoder{}).decodeUniversalValue(tmp, content); err != nil {
+		return err
+	}
+	child.Value = tmp.Value
+	child.ByteValue = content
+	return nil
+}
+
+// Equal reports whether p and other have the same identifier, Value and
+// children, ignoring descriptions. Integer values are compared numerically, so
+// e.g. an INTEGER built with NewBigInt equals the decoded packet holding an
+// int64, and encodings with redundant leading octets equal their minimal form.
+// The content octets of primitive packets are only compared if either Value is
+// nil, e.g. for application-class packets.
+func (p *Packet) Equal(other *Packet) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	if p.Identifier != other.Identifier || len(p.Children) != len(other.Children) {
+		return false
+	}
+	if p.Value != nil && other.Value != nil {
+		if !valuesEqual(p.Value, other.Value) {
+			return false
+		}
+	} else if p.TagType == TypePrimitive && !bytes.Equal(p.Data.Bytes(), other.Data.Bytes()) {
+		return false
+	}
+	for i := range p.Children {
+		if !p.Children[i].Equal(other.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares the Values of two packets, integers numerically and
+// times as instants.
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := bigIntValue(a); ok {
+		y, ok := bigIntValue(b)
+		return ok && x.Cmp(y) == 0
+	}
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		return ok && realsEqual(x, y)
+	case time.Time:
+		y, ok := b.(time.Time)
+		return ok && x.Equal(y)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// UsedIndefiniteLength reports whether the packet or any of its descendants
+// was decoded from an indefinite-length encoding, which DER does not allow.
+// Packets built by the constructors always report false.
+func (p *Packet) UsedIndefiniteLength() bool {
+	if p.indefinite {
+		return true
+	}
+	for _, child := range p.Children {
+		if child.UsedIndefiniteLength() {
+			return true
+		}
+	}
+	return false
+}
+
+// StripDescriptions clears the Description of p and all of its children.
+func (p *Packet) StripDescriptions() {
+	p.Description = ""
+	for _, child := range p.Children {
+		child.StripDescriptions()
+	}
+}
+
+// Clone returns a deep copy of p and its children, so that either can be
+// modified without affecting the other. Values holding slices or pointers,
+// e.g. a *big.Int or a BitString, are copied as well.
+func (p *Packet) Clone() *Packet {
+	c := &Packet{
+		Identifier:       p.Identifier,
+		Value:            cloneValue(p.Value),
+		Description:      p.Description,
+		IndefiniteLength: p.IndefiniteLength,
+		indefinite:       p.indefinite,
+	}
+	if p.ByteValue != nil {
+		c.ByteValue = append([]byte{}, p.ByteValue...)
+	}
+	if p.Data != nil {
+		c.Data = bytes.NewBuffer(append([]byte{}, p.Data.Bytes()...))
+	}
+	if p.Children != nil {
+		c.Children = make([]*Packet, len(p.Children))
+		for i, child := range p.Children {
+			c.Children[i] = child.Clone()
+		}
+	}
+	return c
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler by returning the BER
+// encoding of p. Descriptions are not preserved.
+func (p *Packet) MarshalBinary() ([]byte, error) {
+	return p.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler by decoding data,
+// which must hold exactly one packet, into p.
+func (p *Packet) UnmarshalBinary(data []byte) error {
+	decoded, err := DecodeExact(data)
+	if err != nil {
+		return err
+	}
+	*p = *decoded
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder like MarshalBinary, so decoded trees
+// can be stored in gob-based caches.
+func (p *Packet) GobEncode() ([]byte, error) {
+	return p.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder like UnmarshalBinary.
+func (p *Packet) GobDecode(data []byte) error {
+	return p.UnmarshalBinary(data)
+}
+
+// cloneValue copies the mutable Value types set by the decoder and constructors.
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return append([]byte{}, v...)
+	case []int:
+		return append([]int{}, v...)
+	case *big.Int:
+		return new(big.Int).Set(v)
+	case BitString:
+		return BitString{Bytes: append([]byte{}, v.Bytes...), BitLength: v.BitLength}
+	case net.IP:
+		return append(net.IP{}, v...)
+	}
+	return v
+}
+
 func Encode(classType Class, tagType Type, tag Tag, value interface{}, description string) *Packet {
 	p := new(Packet)
 
@@ -558,6 +1134,19 @@ func NewBoolean(classType Class, tagType Type, tag Tag, value bool, description
 	return p
 }
 
+// NewRawBoolean returns a BOOLEAN packet with arbitrary content octets. It
+// allows producing deliberately malformed booleans, e.g. with more than one
+// octet, to test decoders.
+func NewRawBoolean(classType Class, tagType Type, tag Tag, contents []byte, description string) *Packet {
+	p := Encode(classType, tagType, tag, nil, description)
+
+	val, _ := ParseInt64(contents)
+	p.Value = val != 0
+	p.Data.Write(contents)
+
+	return p
+}
+
 // NewLDAPBoolean returns a RFC 4511-compliant Boolean packet.
 func NewLDAPBoolean(classType Class, tagType Type, tag Tag, value bool, description string) *Packet {
 	intValue := int64(0)
@@ -582,12 +1171,12 @@ func NewInteger(classType Class, tagType Type, tag Tag, value interface{}, descr
 	case int:
 		p.Data.Write(encodeInteger(int64(v)))
 	case uint:
-		p.Data.Write(encodeInteger(int64(v)))
+		p.Data.Write(encodeBigInt(new(big.Int).SetUint64(uint64(v))))
 	case int64:
 		p.Data.Write(encodeInteger(v))
 	case uint64:
-		// TODO : check range or add encodeUInt...
-		p.Data.Write(encodeInteger(int64(v)))
+		// values above math.MaxInt64 need a leading zero octet
+		p.Data.Write(encodeBigInt(new(big.Int).SetUint64(v)))
 	case int32:
 		p.Data.Write(encodeInteger(int64(v)))
 	case uint32:
@@ -608,6 +1197,16 @@ func NewInteger(classType Class, tagType Type, tag Tag, value interface{}, descr
 	return p
 }
 
+// NewEnumerated returns an ENUMERATED packet holding value.
+func NewEnumerated(classType Class, tagType Type, tag Tag, value int64, description string) *Packet {
+	p := Encode(classType, tagType, tag, nil, description)
+
+	p.Value = value
+	p.Data.Write(minimalTwosComplement(value))
+
+	return p
+}
+
 func NewString(classType Class, tagType Type, tag Tag, value, description string) *Packet {
 	p := Encode(classType, tagType, tag, nil, description)
 
@@ -617,26 +1216,27 @@ func NewString(classType Class, tagType Type, tag Tag, value, description string
 	return p
 }
 
+// NewGeneralizedTime returns a GeneralizedTime packet holding value in UTC.
+// Fractional seconds are encoded without trailing zeros (x.690, 11.7).
 func NewGeneralizedTime(classType Class, tagType Type, tag Tag, value time.Time, description string) *Packet {
 	p := Encode(classType, tagType, tag, nil, description)
-	var s string
-	if value.Nanosecond() != 0 {
-		s = value.Format(`20060102150405.000000000Z`)
-	} else {
-		s = value.Format(`20060102150405Z`)
-	}
+	s := value.UTC().Format(`20060102150405.999999999Z`)
 	p.Value = s
 	p.Data.Write([]byte(s))
 	return p
 }
 
+// NewReal returns a REAL packet holding value, which must be a float64 or a
+// float32. The packet's Value holds the value as float64.
 func NewReal(classType Class, tagType Type, tag Tag, value interface{}, description string) *Packet {
 	p := Encode(classType, tagType, tag, nil, description)
 
 	switch v := value.(type) {
 	case float64:
+		p.Value = v
 		p.Data.Write(encodeFloat(v))
 	case float32:
+		p.Value = float64(v)
 		p.Data.Write(encodeFloat(float64(v)))
 	default:
 		panic(fmt.Sprintf("Invalid type %T, expected float{64|32}", v))
@@ -650,6 +1250,15 @@ func NewOID(classType Class, tagType Type, tag Tag, value interface{}, descripti
 	switch v := value.(type) {
 	case string:
 		encoded, err := encodeOID(v)
+		if err != nil {
+			// Arcs too large for an int, e.g. UUIDs below 2.25, are encoded with big.Int
+			if oid, bigErr := parseBigOIDString(v); bigErr == nil {
+				encoded, bigErr = encodeBigOID(oid)
+				if bigErr == nil {
+					err = nil
+				}
+			}
+		}
 		if err != nil {
 			fmt.Printf("failed writing %v", err)
 			return nil
@@ -663,22 +1272,32 @@ func NewOID(classType Class, tagType Type, tag Tag, value interface{}, descripti
 	return p
 }
 
+// NewRelativeOID returns a RELATIVE-OID packet holding value, given either as
+// a dotted string like "8571.3.2" or as []int. Unlike an OBJECT IDENTIFIER,
+// every arc is encoded as a separate subidentifier (x.690, 8.20). The packet's
+// Value holds the arcs as []int, as does a decoded RELATIVE-OID. nil is
+// returned if value is not a valid RELATIVE-OID, e.g. has a negative arc.
 func NewRelativeOID(classType Class, tagType Type, tag Tag, value interface{}, description string) *Packet {
 	p := Encode(classType, tagType, tag, nil, description)
 
+	var arcs []int
 	switch v := value.(type) {
 	case string:
-		encoded, err := encodeRelativeOID(v)
-		if err != nil {
-			fmt.Printf("failed writing %v", err)
+		var err error
+		if arcs, err = parseOIDString(v); err != nil {
 			return nil
 		}
-		p.Value = v
-		p.Data.Write(encoded)
-		// TODO: support []int already ?
+	case []int:
+		arcs = append([]int(nil), v...)
 	default:
-		panic(fmt.Sprintf("Invalid type %T, expected float{64|32}", v))
+		panic(fmt.Sprintf("Invalid type %T, expected string or []int", v))
+	}
+	encoded, err := encodeRelativeOIDArcs(arcs)
+	if err != nil {
+		return nil
 	}
+	p.Value = arcs
+	p.Data.Write(encoded)
 	return p
 }
 
@@ -694,8 +1313,8 @@ func encodeOID(oidString string) ([]byte, error) {
 		}
 		oid[i] = val
 	}
-	if len(oid) < 2 || oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
-		panic(fmt.Sprintf("invalid object identifier % d", oid)) // TODO: not elegant
+	if err := validateObjectIdentifier(oid); err != nil {
+		return nil, err
 	}
 	encoded := make([]byte, 0)
 
@@ -708,6 +1327,14 @@ func encodeOID(oidString string) ([]byte, error) {
 }
 
 func encodeRelativeOID(oidString string) ([]byte, error) {
+	oid, err := parseOIDString(oidString)
+	if err != nil {
+		return nil, err
+	}
+	return encodeRelativeOIDArcs(oid)
+}
+
+func parseOIDString(oidString string) ([]int, error) {
 	parts := strings.Split(oidString, ".")
 	oid := make([]int, len(parts))
 	for i, part := range parts {
@@ -717,10 +1344,20 @@ func encodeRelativeOID(oidString string) ([]byte, error) {
 		}
 		oid[i] = val
 	}
+	return oid, nil
+}
 
+// encodeRelativeOIDArcs encodes every arc as an independent base-128 subidentifier.
+func encodeRelativeOIDArcs(oid []int) ([]byte, error) {
+	if len(oid) == 0 {
+		return nil, errors.New("zero length RELATIVE OID")
+	}
 	encoded := make([]byte, 0)
 
 	for i := 0; i < len(oid); i++ {
+		if oid[i] < 0 {
+			return nil, fmt.Errorf("invalid RELATIVE OID arc %d", oid[i])
+		}
 		encoded = appendBase128Int(encoded, int64(oid[i]))
 	}
 
@@ -811,6 +1448,29 @@ func parseObjectIdentifier(bytes []byte) (s []int, err error) {
 	return
 }
 
+// validateObjectIdentifier checks the structure of the first two arcs of an
+// OBJECT IDENTIFIER before it is encoded: the first arc is 0, 1 or 2, and the
+// second arc is at most 39 unless the first arc is 2 (x.690, 8.19.4). Decoded
+// identifiers always satisfy this, as the packing of the first two arcs cannot
+// express anything else.
+func validateObjectIdentifier(oid []int) error {
+	if len(oid) < 2 {
+		return fmt.Errorf("OBJECT IDENTIFIER must have at least two arcs")
+	}
+	for i, v := range oid {
+		if v < 0 {
+			return fmt.Errorf("negative arc %d in OBJECT IDENTIFIER at position %d", v, i)
+		}
+	}
+	if oid[0] > 2 {
+		return fmt.Errorf("invalid first arc %d in OBJECT IDENTIFIER", oid[0])
+	}
+	if oid[0] < 2 && oid[1] > 39 {
+		return fmt.Errorf("invalid second arc %d in OBJECT IDENTIFIER for first arc %d", oid[1], oid[0])
+	}
+	return nil
+}
+
 func parseRelativeObjectIdentifier(bytes []byte) (s []int, err error) {
 	if len(bytes) == 0 {
 		err = fmt.Errorf("zero length RELATIVE OBJECT IDENTIFIER")
@@ -834,6 +1494,10 @@ func parseRelativeObjectIdentifier(bytes []byte) (s []int, err error) {
 
 // parseBase128Int parses a base-128 encoded int from the given offset in the
 // given byte slice. It returns the value and the new offset.
+// errBase128TooLarge is returned by parseBase128Int for integers that don't fit
+// in an int32, which parseBase128BigInt may still be able to parse.
+var errBase128TooLarge = errors.New("base 128 integer too large")
+
 func parseBase128Int(bytes []byte, initOffset int) (ret, offset int, err error) {
 	offset = initOffset
 	var ret64 int64
@@ -841,7 +1505,7 @@ func parseBase128Int(bytes []byte, initOffset int) (ret, offset int, err error)
 		// 5 * 7 bits per byte == 35 bits of data
 		// Thus the representation is either non-minimal or too large for an int32
 		if shifted == 5 {
-			err = fmt.Errorf("base 128 integer too large")
+			err = errBase128TooLarge
 			return
 		}
 		ret64 <<= 7
@@ -858,7 +1522,7 @@ func parseBase128Int(bytes []byte, initOffset int) (ret, offset int, err error)
 			ret = int(ret64)
 			// Ensure that the returned value fits in an int on all platforms
 			if ret64 > math.MaxInt32 {
-				err = fmt.Errorf("base 128 integer too large")
+				err = errBase128TooLarge
 			}
 			return
 		}
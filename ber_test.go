@@ -2,9 +2,17 @@ package ber
 
 import (
 	"bytes"
+	"encoding"
+	"encoding/gob"
+	"errors"
 	"io"
+	"io/ioutil"
 	"math"
+	"math/big"
+	"math/rand"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEncodeDecodeInteger(t *testing.T) {
@@ -202,6 +210,50 @@ func buff(bs ...byte) *bytes.Reader {
 	return bytes.NewReader(bs)
 }
 
+func TestDecodeErrorOffset(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		offset int
+		cause  error
+	}{
+		// SEQUENCE { INTEGER 1, INTEGER 2, OCTET STRING truncated after two of five bytes }
+		{"truncated third child", []byte{0x30, 0x0b, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x04, 0x05, 'a', 'b'}, 8, io.ErrUnexpectedEOF},
+		// SEQUENCE { INTEGER 1 } with the second child missing
+		{"missing child", []byte{0x30, 0x06, 0x02, 0x01, 0x01}, 5, io.ErrUnexpectedEOF},
+		{"truncated header", []byte{0x04}, 0, io.ErrUnexpectedEOF},
+		// SEQUENCE { INTEGER 1, INTEGER 2, REAL with an invalid NR form }
+		{"invalid third child", []byte{0x30, 0x09, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x09, 0x01, 0x00}, 8, nil},
+		// SEQUENCE { SEQUENCE { NULL, REAL with an invalid NR form } }
+		{"nested", []byte{0x30, 0x07, 0x30, 0x05, 0x05, 0x00, 0x09, 0x01, 0x00}, 6, nil},
+	} {
+		for name, decode := range map[string]func([]byte) error{
+			"DecodePacketErr": func(data []byte) error { _, err := DecodePacketErr(data); return err },
+			"ReadPacket":      func(data []byte) error { _, err := ReadPacket(bytes.NewReader(data)); return err },
+		} {
+			err := decode(tc.data)
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Errorf("%s: %s: expected a DecodeError, got %v", tc.name, name, err)
+				continue
+			}
+			if decodeErr.Offset != tc.offset {
+				t.Errorf("%s: %s: expected offset %d, got %d (%v)", tc.name, name, tc.offset, decodeErr.Offset, err)
+			}
+			if tc.cause != nil && !errors.Is(err, tc.cause) {
+				t.Errorf("%s: %s: expected %v, got %v", tc.name, name, tc.cause, err)
+			}
+			if err.Error() != decodeErr.Err.Error() {
+				t.Errorf("%s: %s: expected the message of the cause, got %q", tc.name, name, err)
+			}
+		}
+	}
+
+	if _, err := ReadPacket(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("expected a plain io.EOF for empty input, got %v", err)
+	}
+}
+
 func TestEOF(t *testing.T) {
 	_, err := ReadPacket(buff())
 	if err != io.EOF {
@@ -249,8 +301,930 @@ func TestEOF(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		_, err := ReadPacket(tc.buf)
-		if err != io.ErrUnexpectedEOF {
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
 			t.Errorf("%s: expected UnexpectedEOF, got %s", tc.name, err)
 		}
 	}
 }
+
+func TestStripDescriptions(t *testing.T) {
+	sequence := NewSequence("a sequence")
+	sequence.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "value", "String"))
+	inner := NewSequence("inner sequence")
+	inner.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, 42, "Integer"))
+	sequence.AppendChild(inner)
+
+	before := sequence.Bytes()
+	sequence.StripDescriptions()
+
+	if sequence.Description != "" || sequence.Children[0].Description != "" ||
+		inner.Description != "" || inner.Children[0].Description != "" {
+		t.Error("descriptions should have been cleared")
+	}
+	if !bytes.Equal(before, sequence.Bytes()) {
+		t.Error("stripping descriptions should not change the encoding")
+	}
+}
+
+func TestAppendNilChild(t *testing.T) {
+	sequence := NewSequence("a sequence")
+	sequence.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, 1, ""))
+	before := sequence.Bytes()
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected AppendChild(nil) to panic")
+			}
+		}()
+		sequence.AppendChild(nil)
+	}()
+
+	if len(sequence.Children) != 1 {
+		t.Errorf("expected 1 child after rejected append, got %d", len(sequence.Children))
+	}
+	if !bytes.Equal(before, sequence.Bytes()) {
+		t.Error("rejected append should not change the encoding")
+	}
+}
+
+func BenchmarkDecodeSequenceOf(b *testing.B) {
+	sequence := NewSequence("")
+	for i := 0; i < 1000; i++ {
+		sequence.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, int64(i), ""))
+	}
+	data := sequence.Bytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodePacketErr(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	inner := NewSequence("inner")
+	inner.IndefiniteLength = true
+	inner.AppendChild(Integer(1))
+	sequence := NewSequence("")
+	sequence.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, string(make([]byte, 200)), ""))
+	sequence.AppendChild(inner)
+
+	for _, p := range []*Packet{sequence, inner, Integer(5)} {
+		var buf bytes.Buffer
+		n, err := p.WriteTo(&buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(p.Bytes(), buf.Bytes()) {
+			t.Errorf("expected % X, got % X", p.Bytes(), buf.Bytes())
+		}
+		if n != int64(p.Size()) {
+			t.Errorf("expected %d bytes written, got %d", p.Size(), n)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	long := NewSequence("long form length")
+	for i := 0; i < 100; i++ {
+		long.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "some value", ""))
+	}
+	indefinite := NewSequence("")
+	indefinite.IndefiniteLength = true
+	indefinite.AppendChild(long)
+	created := time.Date(2024, 2, 29, 23, 15, 30, 0, time.UTC)
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	for i, p := range []*Packet{
+		Encode(ClassUniversal, TypePrimitive, TagNULL, nil, ""),
+		Null(),
+		Bool(true),
+		Integer(math.MinInt64),
+		Enumerated(7),
+		String(string(make([]byte, 300))),
+		NewBoolean(ClassContext, TypePrimitive, 1, false, ""),
+		NewLDAPBoolean(ClassUniversal, TypePrimitive, TagBoolean, true, ""),
+		NewInteger(ClassApplication, TypePrimitive, 2, uint32(math.MaxUint32), ""),
+		NewInteger(ClassPrivate, TypePrimitive, 200000, 1, "multi-octet tag"),
+		NewIntegerWidth(ClassUniversal, TypePrimitive, TagInteger, 1, 8, ""),
+		NewBigInt(ClassUniversal, TypePrimitive, TagInteger, huge, ""),
+		NewUint64(ClassApplication, TypePrimitive, 6, math.MaxUint64, ""),
+		NewString(ClassUniversal, TypePrimitive, TagUTF8String, "päckchen", ""),
+		NewGeneralizedTime(ClassUniversal, TypePrimitive, TagGeneralizedTime, created, ""),
+		NewUTCTime(ClassUniversal, TypePrimitive, TagUTCTime, created, ""),
+		NewReal(ClassUniversal, TypePrimitive, TagRealFloat, 3.14, ""),
+		NewOID(ClassUniversal, TypePrimitive, TagObjectIdentifier, "1.2.840.113549.1.1.11", ""),
+		NewRelativeOID(ClassUniversal, TypePrimitive, TagRelativeOID, "8716.1.2", ""),
+		NewBitString(ClassUniversal, TypePrimitive, TagBitString, BitString{Bytes: []byte{0xA0}, BitLength: 3}, ""),
+		NewBooleanFlags("", []bool{true, false, true}),
+		NewLDAPResult(0, "cn=admin", ""),
+		NewValidity(created, created.AddDate(1, 0, 0)),
+		NewAlgorithmIdentifierNull(OID{1, 2, 840, 113549, 1, 1, 11}),
+		NewVarBindList([]VarBind{{OID: OID{1, 3, 6, 1, 2, 1, 1, 1, 0}}}),
+		long,
+		indefinite,
+	} {
+		if n := len(p.Bytes()); p.Size() != n {
+			t.Errorf("%d (%s): expected size %d, got %d", i, p.Description, n, p.Size())
+		}
+	}
+}
+
+func benchmarkSequence() *Packet {
+	sequence := NewSequence("")
+	for i := 0; i < 1000; i++ {
+		sequence.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "some value", ""))
+	}
+	return sequence
+}
+
+func BenchmarkBytes(b *testing.B) {
+	sequence := benchmarkSequence()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ioutil.Discard.Write(sequence.Bytes())
+	}
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	sequence := benchmarkSequence()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := sequence.WriteTo(ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestEncodeFiltered(t *testing.T) {
+	sequence := NewSequence("a sequence")
+	sequence.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "secret", "password"))
+	sequence.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, 7, "id"))
+	inner := NewSequence("inner sequence")
+	inner.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "token", "token"))
+	inner.AppendChild(NewBoolean(ClassUniversal, TypePrimitive, TagBoolean, true, "flag"))
+	sequence.AppendChild(inner)
+
+	out := sequence.EncodeFiltered(func(p *Packet) bool {
+		return !(p.ClassType == ClassUniversal && p.Tag == TagOctetString)
+	})
+
+	if bytes.Contains(out, []byte("secret")) || bytes.Contains(out, []byte("token")) {
+		t.Errorf("redacted strings should be absent from output: % X", out)
+	}
+
+	decoded, err := DecodePacketErr(out)
+	if err != nil {
+		t.Fatalf("unexpected error decoding filtered output: %v", err)
+	}
+	if len(decoded.Children) != 2 {
+		t.Fatalf("expected 2 children, got %d", len(decoded.Children))
+	}
+	if v, ok := decoded.Children[0].Value.(int64); !ok || v != 7 {
+		t.Errorf("expected integer 7, got %v", decoded.Children[0].Value)
+	}
+	if len(decoded.Children[1].Children) != 1 || decoded.Children[1].Children[0].Value != true {
+		t.Errorf("expected inner sequence with only the boolean, got %d children", len(decoded.Children[1].Children))
+	}
+
+	if !bytes.Equal(sequence.Bytes(), sequence.EncodeFiltered(func(*Packet) bool { return true })) {
+		t.Error("keeping every node should produce the original encoding")
+	}
+
+	// SEQUENCE (indefinite) { INTEGER 1, SEQUENCE (indefinite) { BOOLEAN TRUE, OCTET STRING "x" }, [0] (indefinite) {} }
+	indefinite := NewSequence("")
+	indefinite.IndefiniteLength = true
+	indefinite.AppendChild(Integer(1))
+	nested := NewSequence("")
+	nested.IndefiniteLength = true
+	nested.AppendChild(NewBoolean(ClassUniversal, TypePrimitive, TagBoolean, true, ""))
+	nested.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "x", ""))
+	indefinite.AppendChild(nested)
+	empty := Encode(ClassContext, TypeConstructed, 0, nil, "")
+	empty.IndefiniteLength = true
+	indefinite.AppendChild(empty)
+
+	if out := indefinite.EncodeFiltered(func(*Packet) bool { return true }); !bytes.Equal(indefinite.Bytes(), out) {
+		t.Errorf("expected the indefinite length form to be kept\nwant: % X\ngot:  % X", indefinite.Bytes(), out)
+	}
+	expected := []byte{
+		0x30, 0x80, 0x02, 0x01, 0x01,
+		0x30, 0x80, 0x01, 0x01, 0x01, 0x00, 0x00,
+		0xa0, 0x80, 0x00, 0x00,
+		0x00, 0x00,
+	}
+	out = indefinite.EncodeFiltered(func(p *Packet) bool { return p.Tag != TagOctetString })
+	if !bytes.Equal(expected, out) {
+		t.Errorf("expected % X, got % X", expected, out)
+	}
+}
+
+func TestObjectIdentifierFirstArcs(t *testing.T) {
+	for _, tc := range []struct {
+		oid     string
+		encoded []byte
+	}{
+		{"0.39", []byte{0x27}},
+		{"1.39", []byte{0x4F}},
+		{"2.0", []byte{0x50}},
+		{"2.47", []byte{0x7F}},
+		{"2.48", []byte{0x81, 0x00}},
+		{"2.100", []byte{0x81, 0x34}},
+	} {
+		enc, err := encodeOID(tc.oid)
+		if err != nil {
+			t.Errorf("%s: unexpected error encoding: %v", tc.oid, err)
+			continue
+		}
+		if !bytes.Equal(tc.encoded, enc) {
+			t.Errorf("%s: expected encoding % X, got % X", tc.oid, tc.encoded, enc)
+		}
+		parsed, err := parseObjectIdentifier(enc)
+		if err != nil {
+			t.Errorf("%s: unexpected error parsing: %v", tc.oid, err)
+			continue
+		}
+		if OIDToString(parsed) != tc.oid {
+			t.Errorf("%s: parsed as %s", tc.oid, OIDToString(parsed))
+		}
+	}
+
+	for _, oid := range []string{"1", "3.1", "0.40", "1.99", "1.2.-3"} {
+		if _, err := encodeOID(oid); err == nil {
+			t.Errorf("%s: expected encoding error", oid)
+		}
+	}
+}
+
+func TestEmptyOctetStringAndSequence(t *testing.T) {
+	str := NewString(ClassUniversal, TypePrimitive, TagOctetString, "", "empty string")
+	if !bytes.Equal([]byte{0x04, 0x00}, str.Bytes()) {
+		t.Errorf("unexpected encoding of empty OCTET STRING: % X", str.Bytes())
+	}
+	decoded, err := DecodePacketErr(str.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error decoding empty OCTET STRING: %v", err)
+	}
+	if v, ok := decoded.Value.(string); !ok || v != "" {
+		t.Errorf("expected empty string value, got %#v", decoded.Value)
+	}
+	if decoded.ByteValue == nil || len(decoded.ByteValue) != 0 {
+		t.Errorf("expected empty, non-nil ByteValue, got %#v", decoded.ByteValue)
+	}
+
+	seq := NewSequence("empty sequence")
+	if !bytes.Equal([]byte{0x30, 0x00}, seq.Bytes()) {
+		t.Errorf("unexpected encoding of empty SEQUENCE: % X", seq.Bytes())
+	}
+	decoded, err = DecodePacketErr(seq.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error decoding empty SEQUENCE: %v", err)
+	}
+	if len(decoded.Children) != 0 || decoded.Data.Len() != 0 {
+		t.Errorf("expected no children and no data, got %d children and %d bytes", len(decoded.Children), decoded.Data.Len())
+	}
+	if !bytes.Equal(seq.Bytes(), decoded.Bytes()) {
+		t.Errorf("empty SEQUENCE should re-encode identically, got % X", decoded.Bytes())
+	}
+}
+
+func TestDecodePacketLengthExceedsData(t *testing.T) {
+	for _, data := range [][]byte{
+		{0x04, 0x0A, 0x00, 0x01},
+		{0x30, 0x05, 0x04, 0x0A, 0x00, 0x01},
+		{0x04, 0x84, 0x7F, 0xFF, 0xFF, 0xFF, 0x00},
+	} {
+		p, err := DecodePacketErr(data)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("% X: expected UnexpectedEOF, got %v", data, err)
+		}
+		if p != nil {
+			t.Errorf("% X: expected no packet", data)
+		}
+		if DecodePacket(data) != nil {
+			t.Errorf("% X: expected DecodePacket to return nil", data)
+		}
+	}
+}
+
+func TestLDAPBooleanEncoding(t *testing.T) {
+	if b := NewLDAPBoolean(ClassUniversal, TypePrimitive, TagBoolean, true, "").Bytes(); !bytes.Equal([]byte{0x01, 0x02, 0x00, 0xFF}, b) {
+		t.Errorf("unexpected encoding of true: % X", b)
+	}
+	if b := NewLDAPBoolean(ClassUniversal, TypePrimitive, TagBoolean, false, "").Bytes(); !bytes.Equal([]byte{0x01, 0x01, 0x00}, b) {
+		t.Errorf("unexpected encoding of false: % X", b)
+	}
+}
+
+func TestSetPreservesOrder(t *testing.T) {
+	// SET { INTEGER 2, INTEGER 1 }, not sorted
+	data := []byte{0x31, 0x06, 0x02, 0x01, 0x02, 0x02, 0x01, 0x01}
+
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Children) != 2 || p.Children[0].Value != int64(2) || p.Children[1].Value != int64(1) {
+		t.Errorf("expected children in wire order 2, 1")
+	}
+	if !bytes.Equal(data, p.Bytes()) {
+		t.Errorf("expected re-encoding in wire order\nwant: % X\ngot:  % X", data, p.Bytes())
+	}
+
+	sorted := []byte{0x31, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02}
+	if !bytes.Equal(sorted, p.CanonicalBytes()) {
+		t.Errorf("expected sorted canonical encoding\nwant: % X\ngot:  % X", sorted, p.CanonicalBytes())
+	}
+}
+
+func TestBytesWithLimit(t *testing.T) {
+	sequence := NewSequence("a sequence")
+	for i := 0; i < 50; i++ {
+		sequence.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "value", ""))
+	}
+	size := len(sequence.Bytes())
+	if sequence.ByteLen() != size {
+		t.Fatalf("expected ByteLen %d, got %d", size, sequence.ByteLen())
+	}
+
+	b, err := sequence.BytesWithLimit(size)
+	if err != nil {
+		t.Errorf("unexpected error at the limit: %v", err)
+	}
+	if !bytes.Equal(sequence.Bytes(), b) {
+		t.Error("BytesWithLimit should return the same encoding as Bytes")
+	}
+
+	if _, err := sequence.BytesWithLimit(size - 1); err == nil {
+		t.Error("expected error just over the limit")
+	}
+}
+
+func TestDecodePacketString(t *testing.T) {
+	data := "\x30\x08\x04\x03abc\x02\x01\x05"
+
+	p := DecodePacketString(data)
+	if p == nil || len(p.Children) != 2 {
+		t.Fatal("expected a sequence with two children")
+	}
+	if p.Children[0].Value != "abc" || p.Children[1].Value != int64(5) {
+		t.Errorf("unexpected values %v, %v", p.Children[0].Value, p.Children[1].Value)
+	}
+
+	p, err := ReadPacketString(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(p.Bytes()) != data {
+		t.Errorf("expected re-encoding to match the source, got % X", p.Bytes())
+	}
+
+	if _, err := ReadPacketString(data[:5]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected UnexpectedEOF, got %v", err)
+	}
+}
+
+func TestReinterpretChild(t *testing.T) {
+	// SEQUENCE { [0] 0x01 0x00, [1] "abc" }
+	data := []byte{0x30, 0x09, 0x80, 0x02, 0x01, 0x00, 0x81, 0x03, 'a', 'b', 'c'}
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Children[0].Value != nil {
+		t.Fatalf("expected no value for a context-tagged child, got %v", p.Children[0].Value)
+	}
+
+	if err := p.ReinterpretChild(0, TagInteger); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, ok := p.Children[0].Value.(int64); !ok || v != 256 {
+		t.Errorf("expected integer 256, got %#v", p.Children[0].Value)
+	}
+	if err := p.ReinterpretChild(1, TagUTF8String); err != nil || p.Children[1].Value != "abc" {
+		t.Errorf("expected string abc, got %#v (%v)", p.Children[1].Value, err)
+	}
+	if !bytes.Equal(data, p.Bytes()) {
+		t.Error("reinterpreting should not change the encoding")
+	}
+
+	if err := p.ReinterpretChild(2, TagInteger); err == nil {
+		t.Error("expected error for an out of range index")
+	}
+	if err := p.ReinterpretChild(1, TagRealFloat); err == nil {
+		t.Error("expected error reinterpreting a string as REAL")
+	}
+}
+
+func TestReadPacketMulti(t *testing.T) {
+	data := NewLDAPResult(0, "cn=admin", "").Bytes()
+	first := bytes.NewReader(data[:5])
+	second := bytes.NewReader(append(append([]byte{}, data[5:]...), 0x05, 0x00))
+
+	p, err := ReadPacketMulti(first, second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, p.Bytes()) {
+		t.Errorf("expected % X, got % X", data, p.Bytes())
+	}
+	if second.Len() != 2 {
+		t.Errorf("expected the trailing packet to remain unread, %d bytes left", second.Len())
+	}
+
+	_, err = ReadPacketMulti(bytes.NewReader(data[:3]), bytes.NewReader(data[3:6]))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestUsedIndefiniteLength(t *testing.T) {
+	definite := []byte{0x30, 0x08, 0x30, 0x06, 0x02, 0x01, 0x01, 0x04, 0x01, 'a'}
+	indefinite := []byte{0x30, 0x0a, 0x30, 0x80, 0x02, 0x01, 0x01, 0x04, 0x01, 'a', 0x00, 0x00}
+
+	p, err := DecodePacketErr(definite)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.UsedIndefiniteLength() {
+		t.Error("expected definite-length tree to report false")
+	}
+
+	p, err = DecodePacketErr(indefinite)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !p.UsedIndefiniteLength() {
+		t.Error("expected nested indefinite length to be reported")
+	}
+	if p.Children[0].Children[0].UsedIndefiniteLength() {
+		t.Error("expected definite-length child to report false")
+	}
+	if !bytes.Equal(definite, p.Bytes()) {
+		t.Errorf("expected re-encoding with definite length, got % X", p.Bytes())
+	}
+}
+
+func TestRelativeOID(t *testing.T) {
+	// 8571 = 66 * 128 + 123, the first two arcs are not combined as for an OBJECT IDENTIFIER
+	expected := []byte{0x0d, 0x04, 0xc2, 0x7b, 0x03, 0x02}
+
+	for _, value := range []interface{}{"8571.3.2", []int{8571, 3, 2}} {
+		p := NewRelativeOID(ClassUniversal, TypePrimitive, TagRelativeOID, value, "")
+		if !bytes.Equal(expected, p.Bytes()) {
+			t.Errorf("%v: expected % X, got % X", value, expected, p.Bytes())
+		}
+	}
+
+	p, err := DecodePacketErr(expected)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	arcs, ok := p.Value.([]int)
+	if !ok || len(arcs) != 3 || arcs[0] != 8571 || arcs[1] != 3 || arcs[2] != 2 {
+		t.Errorf("expected [8571 3 2], got %#v", p.Value)
+	}
+
+	if _, err := encodeRelativeOID("1.-2"); err == nil {
+		t.Error("expected error for a negative arc")
+	}
+	for _, value := range []interface{}{"1.x", []int{1, -2}} {
+		if p := NewRelativeOID(ClassUniversal, TypePrimitive, TagRelativeOID, value, ""); p != nil {
+			t.Errorf("%v: expected nil, got % X", value, p.Bytes())
+		}
+	}
+}
+
+func TestEncodeOIDInvalidFirstArcs(t *testing.T) {
+	for _, oid := range []string{"3.5", "0.99", "1.40", "1"} {
+		if enc, err := encodeOID(oid); err == nil {
+			t.Errorf("%s: expected error, got % X", oid, enc)
+		}
+	}
+	if p := NewOID(ClassUniversal, TypePrimitive, TagObjectIdentifier, "3.5", ""); p != nil {
+		t.Error("expected no packet for an invalid OID")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := NewLDAPResult(0, "cn=admin", "")
+	b, err := DecodePacketErr(a.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !a.Equal(b) {
+		t.Error("expected decoded packet to equal the original")
+	}
+	if a.Equal(NewLDAPResult(0, "cn=other", "")) {
+		t.Error("expected packets with different strings to differ")
+	}
+	if a.Equal(nil) || !(*Packet)(nil).Equal(nil) {
+		t.Error("unexpected result comparing with nil")
+	}
+}
+
+func TestEqualStructure(t *testing.T) {
+	build := func(first, second *Packet) *Packet {
+		inner := NewSequence("inner")
+		inner.AppendChild(first)
+		inner.AppendChild(second)
+		seq := NewSequence("built")
+		seq.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "abc", "value"))
+		seq.AppendChild(inner)
+		return seq
+	}
+	built := build(Integer(1), Bool(true))
+
+	decoded, err := DecodePacketErr(built.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded.StripDescriptions()
+	if !built.Equal(decoded) || !decoded.Equal(built) {
+		t.Error("expected the decoded SEQUENCE to equal the hand-built one")
+	}
+
+	for name, other := range map[string]*Packet{
+		"child order": build(Bool(true), Integer(1)),
+		"value":       build(Integer(2), Bool(true)),
+		"class":       build(NewInteger(ClassContext, TypePrimitive, TagInteger, 1, ""), Bool(true)),
+		"type":        build(NewInteger(ClassUniversal, TypeConstructed, TagInteger, 1, ""), Bool(true)),
+		"tag":         build(Enumerated(1), Bool(true)),
+	} {
+		if built.Equal(other) {
+			t.Errorf("%s: expected packets to differ", name)
+		}
+	}
+
+	// Values are compared rather than encodings: 1.5 as decimal and as binary REAL
+	binary, err := DecodePacketErr([]byte{0x09, 0x03, 0x80, 0xFF, 0x03})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if real := NewReal(ClassUniversal, TypePrimitive, TagRealFloat, 1.5, ""); !real.Equal(binary) {
+		t.Errorf("expected % X to equal % X", real.Bytes(), binary.Bytes())
+	}
+
+	// equal integer Values don't hide different children
+	a := NewSequence("")
+	a.Value = int64(1)
+	a.AppendChild(Integer(1))
+	b := NewSequence("")
+	b.Value = int64(1)
+	b.AppendChild(Integer(2))
+	if a.Equal(b) {
+		t.Error("expected packets with different children to differ")
+	}
+}
+
+func TestReadAllPackets(t *testing.T) {
+	var stream []byte
+	for _, v := range []string{"first", "second", "third"} {
+		stream = append(stream, String(v).Bytes()...)
+	}
+
+	packets, err := ReadAllPackets(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(packets) != 3 {
+		t.Fatalf("expected 3 packets, got %d", len(packets))
+	}
+	for i, expected := range []string{"first", "second", "third"} {
+		if packets[i].Value != expected {
+			t.Errorf("%d: expected %q, got %v", i, expected, packets[i].Value)
+		}
+	}
+
+	packets, err = ReadAllPackets(bytes.NewReader(stream[:len(stream)-1]))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF for a truncated last packet, got %v", err)
+	}
+	if len(packets) != 2 {
+		t.Errorf("expected the 2 complete packets, got %d", len(packets))
+	}
+
+	if packets, err := ReadAllPackets(bytes.NewReader(nil)); err != nil || len(packets) != 0 {
+		t.Errorf("expected no packets and no error for an empty stream, got %d (%v)", len(packets), err)
+	}
+}
+
+func TestDecodeExact(t *testing.T) {
+	data := NewLDAPResult(0, "cn=admin", "").Bytes()
+
+	if p, err := DecodeExact(data); err != nil || len(p.Children) != 3 {
+		t.Errorf("unexpected result %v (%v)", p, err)
+	}
+	if _, err := DecodeExact(append(data, 0xDE)); err == nil || err.Error() != "trailing data after packet: 1 bytes" {
+		t.Errorf("expected trailing data error, got %v", err)
+	}
+	if p := DecodePacket(append(data, 0xDE, 0xAD)); p == nil {
+		t.Error("expected DecodePacket to ignore trailing data")
+	}
+	if _, err := DecodeExact(data[:len(data)-1]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestBinaryMarshaler(t *testing.T) {
+	var _ encoding.BinaryMarshaler = (*Packet)(nil)
+	var _ encoding.BinaryUnmarshaler = (*Packet)(nil)
+
+	result := NewLDAPResult(0, "cn=admin", "")
+	data, err := result.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var p Packet
+	if err := p.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !p.Equal(result) {
+		t.Errorf("expected % X, got % X", data, p.Bytes())
+	}
+
+	if err := new(Packet).UnmarshalBinary(append(data, 0x05, 0x00)); err == nil {
+		t.Error("expected error for trailing data")
+	}
+	if err := new(Packet).UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+
+	if err := new(Packet).GobDecode(append(data, 0x05, 0x00)); err == nil {
+		t.Error("expected gob decoding to reject trailing data")
+	}
+}
+
+func TestIndefiniteLengthEncoding(t *testing.T) {
+	inner := NewSequence("inner")
+	inner.IndefiniteLength = true
+	inner.AppendChild(Bool(true))
+
+	outer := NewSequence("outer")
+	outer.IndefiniteLength = true
+	outer.AppendChild(Integer(5))
+	outer.AppendChild(inner)
+
+	expected := []byte{0x30, 0x80, 0x02, 0x01, 0x05, 0x30, 0x80, 0x01, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00}
+	if !bytes.Equal(expected, outer.Bytes()) {
+		t.Errorf("expected % X, got % X", expected, outer.Bytes())
+	}
+	if outer.ByteLen() != len(expected) {
+		t.Errorf("expected ByteLen %d, got %d", len(expected), outer.ByteLen())
+	}
+
+	p, err := DecodePacketErr(outer.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Children) != 2 || len(p.Children[1].Children) != 1 {
+		t.Errorf("expected 2 children with 1 nested child, got %d", len(p.Children))
+	}
+	if !p.UsedIndefiniteLength() {
+		t.Error("expected decoded packet to report indefinite length")
+	}
+
+	primitive := String("abc")
+	primitive.IndefiniteLength = true
+	if !bytes.Equal([]byte{0x04, 0x03, 'a', 'b', 'c'}, primitive.Bytes()) {
+		t.Errorf("expected primitive packet to use definite length, got % X", primitive.Bytes())
+	}
+}
+
+func TestEncodeAt(t *testing.T) {
+	first := NewLDAPResult(0, "cn=admin", "")
+	second := Integer(1 << 20)
+	dst := make([]byte, 2+first.ByteLen()+second.ByteLen())
+
+	end, err := first.EncodeAt(dst, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if end != 2+first.ByteLen() {
+		t.Errorf("expected end offset %d, got %d", 2+first.ByteLen(), end)
+	}
+	if end, err = second.EncodeAt(dst, end); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if end != len(dst) {
+		t.Errorf("expected end offset %d, got %d", len(dst), end)
+	}
+
+	expected := append(append([]byte{0, 0}, first.Bytes()...), second.Bytes()...)
+	if !bytes.Equal(expected, dst) {
+		t.Errorf("expected % X, got % X", expected, dst)
+	}
+
+	if _, err := second.EncodeAt(dst, len(dst)-1); err == nil {
+		t.Error("expected error for too small a buffer")
+	}
+	if _, err := second.EncodeAt(dst, len(dst)+1); err == nil {
+		t.Error("expected error for an offset beyond the buffer")
+	}
+}
+
+func TestReadPacketLimit(t *testing.T) {
+	// OCTET STRING claiming 2 GiB of content
+	if _, err := ReadPacketLimit(bytes.NewReader([]byte{0x04, 0x84, 0x7F, 0xFF, 0xFF, 0xFF}), 1024); err == nil || errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected size limit error, got %v", err)
+	}
+
+	data := NewLDAPResult(0, "cn=admin", "").Bytes()
+	if _, err := ReadPacketLimit(bytes.NewReader(data), len(data)); err != nil {
+		t.Errorf("unexpected error at the exact limit: %v", err)
+	}
+	if _, err := ReadPacketLimit(bytes.NewReader(data), len(data)-1); err == nil {
+		t.Error("expected error one byte below the limit")
+	}
+
+	// children of an indefinite-length SEQUENCE count towards the limit
+	indefinite := []byte{0x30, 0x80}
+	for i := 0; i < 100; i++ {
+		indefinite = append(indefinite, 0x04, 0x02, 'a', 'b')
+	}
+	indefinite = append(indefinite, 0x00, 0x00)
+	if _, err := ReadPacketLimit(bytes.NewReader(indefinite), 64); err == nil {
+		t.Error("expected error for children exceeding the limit")
+	}
+	if p, err := ReadPacketLimit(bytes.NewReader(indefinite), len(indefinite)); err != nil || len(p.Children) != 100 {
+		t.Errorf("expected 100 children within the limit, got %v", err)
+	}
+}
+
+func TestMaxPacketDepth(t *testing.T) {
+	nested := func(depth int) []byte {
+		data := bytes.Repeat([]byte{0x30, 0x80}, depth)
+		return append(data, bytes.Repeat([]byte{0x00, 0x00}, depth)...)
+	}
+
+	deep := nested(5000)
+	if _, err := DecodePacketErr(deep); err != ErrMaxDepthExceeded {
+		t.Errorf("DecodePacketErr: expected ErrMaxDepthExceeded, got %v", err)
+	}
+	if _, err := ReadPacket(bytes.NewReader(deep)); err != ErrMaxDepthExceeded {
+		t.Errorf("ReadPacket: expected ErrMaxDepthExceeded, got %v", err)
+	}
+	if err := Parse(deep, &recordingHandler{}); err != ErrMaxDepthExceeded {
+		t.Errorf("Parse: expected ErrMaxDepthExceeded, got %v", err)
+	}
+
+	if _, err := DecodePacketErr(nested(MaxPacketDepth)); err != nil {
+		t.Errorf("unexpected error at the maximum depth: %v", err)
+	}
+	if _, err := DecodePacketErr(nested(MaxPacketDepth + 1)); err != ErrMaxDepthExceeded {
+		t.Errorf("expected ErrMaxDepthExceeded one level beyond the maximum, got %v", err)
+	}
+}
+
+func TestDecodePacketArbitraryInput(t *testing.T) {
+	seeds := [][]byte{
+		NewLDAPResult(0, "cn=admin", "invalid credentials").Bytes(),
+		NewVarBindList([]VarBind{{OID: OID{1, 3, 6, 1, 2, 1, 1, 5, 0}}}).Bytes(),
+		{0x09, 0x03, 0x80, 0xFF, 0x01},
+		{0x23, 0x80, 0x03, 0x02, 0x00, 0x01, 0x03, 0x02, 0x04, 0x10, 0x00, 0x00},
+		{0x9f, 0x81, 0x48, 0x01, 0x2a},
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		var data []byte
+		if i%2 == 0 {
+			// random bytes
+			data = make([]byte, r.Intn(32))
+			r.Read(data)
+		} else {
+			// valid encodings with a few bytes mutated or cut off
+			data = append([]byte{}, seeds[r.Intn(len(seeds))]...)
+			for j := r.Intn(4); j >= 0; j-- {
+				data[r.Intn(len(data))] = byte(r.Intn(256))
+			}
+			data = data[:r.Intn(len(data)+1)]
+		}
+
+		func() {
+			defer func() {
+				if e := recover(); e != nil {
+					t.Fatalf("% X: decoding panicked: %v", data, e)
+				}
+			}()
+			p, err := DecodePacketErr(data)
+			if (p == nil) == (err == nil) {
+				t.Fatalf("% X: expected either a packet or an error, got %v and %v", data, p, err)
+			}
+			if q := DecodePacket(data); (q == nil) != (p == nil) {
+				t.Fatalf("% X: DecodePacket and DecodePacketErr disagree", data)
+			}
+			if p != nil {
+				p.Bytes()
+			}
+		}()
+	}
+}
+
+func TestPacketString(t *testing.T) {
+	seq := NewSequence("Person")
+	seq.AppendChild(NewString(ClassUniversal, TypePrimitive, TagUTF8String, "Jane", "name"))
+	seq.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, 42, "age"))
+
+	s := seq.String()
+	for _, expected := range []string{
+		"Person: (Universal, Constructed, Sequence and Sequence of)",
+		"\n name: (Universal, Primitive, UTF8 String) Len=4 \"Jane\"\n",
+		"\n age: (Universal, Primitive, Integer) Len=1 \"42\"\n",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("expected %q in\n%s", expected, s)
+		}
+	}
+
+	var buf bytes.Buffer
+	WritePacket(&buf, seq)
+	if buf.String() != s {
+		t.Errorf("expected String to match WritePacket, got\n%s\nand\n%s", s, buf.String())
+	}
+}
+
+func TestClone(t *testing.T) {
+	seq := NewSequence("Sequence")
+	seq.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "abc", "value"))
+	bits := NewBitString(ClassUniversal, TypePrimitive, TagBitString, BitString{Bytes: []byte{0xA0}, BitLength: 3}, "flags")
+	seq.AppendChild(bits)
+	original, err := DecodePacketErr(seq.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	encoded := original.Bytes()
+
+	clone := original.Clone()
+	if !clone.Equal(original) {
+		t.Fatalf("expected clone to equal the original, got % X", clone.Bytes())
+	}
+
+	clone.AppendChild(Integer(1))
+	clone.Children[0].Value = "changed"
+	clone.Children[0].ByteValue[0] = 'x'
+	clone.Children[0].Data.Reset()
+	clone.Children[1].Value.(BitString).Bytes[0] = 0xFF
+
+	if len(original.Children) != 2 {
+		t.Errorf("expected the original to keep 2 children, got %d", len(original.Children))
+	}
+	if original.Children[0].Value != "abc" || string(original.Children[0].ByteValue) != "abc" {
+		t.Errorf("expected the original value to be unchanged, got %v", original.Children[0].Value)
+	}
+	if b := original.Children[1].Value.(BitString).Bytes[0]; b != 0xA0 {
+		t.Errorf("expected the original bits to be unchanged, got %02X", b)
+	}
+	if !bytes.Equal(encoded, original.Bytes()) {
+		t.Errorf("expected the original encoding % X, got % X", encoded, original.Bytes())
+	}
+}
+
+func TestGob(t *testing.T) {
+	type entry struct {
+		Key    string
+		Packet *Packet
+	}
+	result := NewLDAPResult(32, "ou=people,dc=example,dc=com", "no such object")
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entry{Key: "search", Packet: result}); err != nil {
+		t.Fatalf("unexpected error encoding: %v", err)
+	}
+	var cached entry
+	if err := gob.NewDecoder(&buf).Decode(&cached); err != nil {
+		t.Fatalf("unexpected error decoding: %v", err)
+	}
+	if cached.Key != "search" || !cached.Packet.Equal(result) {
+		t.Errorf("expected % X, got % X", result.Bytes(), cached.Packet.Bytes())
+	}
+	if v := cached.Packet.Children[1].Value; v != "ou=people,dc=example,dc=com" {
+		t.Errorf("expected decoded values, got %v", v)
+	}
+
+	if err := new(Packet).GobDecode([]byte{0x30, 0x05, 0x02}); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
+func TestChildrenCapacityBounded(t *testing.T) {
+	// A SEQUENCE declaring 16 MB of content, with a single child present
+	data := []byte{0x30, 0x84, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01, 0x01}
+	p, err := DecodePacketOptions(data, DecodeOptions{BestEffort: true})
+	if err == nil {
+		t.Fatal("expected error for truncated data")
+	}
+	if p == nil || len(p.Children) != 1 {
+		t.Fatalf("expected partial packet with 1 child, got %v", p)
+	}
+	if cap(p.Children) > 16 {
+		t.Errorf("expected a small Children capacity, got %d", cap(p.Children))
+	}
+}
@@ -0,0 +1,106 @@
+package ber
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NewBooleanFlags packs flags into a universal BIT STRING, the first flag
+// being the most significant bit of the first octet. This is more compact than
+// a SEQUENCE OF BOOLEAN for schemas holding many booleans. The packet's Value
+// is the BitString, as for a decoded BIT STRING; use ParseBooleanFlags to get
+// the flags back.
+func NewBooleanFlags(description string, flags []bool) *Packet {
+	p := Encode(ClassUniversal, TypePrimitive, TagBitString, nil, description)
+
+	content := make([]byte, 1+(len(flags)+7)/8)
+	content[0] = byte((8 - len(flags)%8) % 8)
+	for i, flag := range flags {
+		if flag {
+			content[1+i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+
+	p.Value = BitString{Bytes: content[1:], BitLength: len(flags)}
+	p.Data.Write(content)
+	return p
+}
+
+// ParseBooleanFlags unpacks the content octets of a BIT STRING created by
+// NewBooleanFlags into one boolean per bit.
+func ParseBooleanFlags(v []byte) ([]bool, error) {
+	b, err := ParseBitString(v)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make([]bool, b.BitLength)
+	for i := range flags {
+		flags[i] = b.At(i)
+	}
+	return flags, nil
+}
+
+// BitString holds the value of a BIT STRING. Bits are numbered from the most
+// significant bit of the first octet (x.690, 8.6.2.1).
+type BitString struct {
+	Bytes     []byte
+	BitLength int
+}
+
+// At returns the bit at index i, or false if i is out of range.
+func (b BitString) At(i int) bool {
+	if i < 0 || i >= b.BitLength {
+		return false
+	}
+	return b.Bytes[i/8]&(0x80>>uint(i%8)) != 0
+}
+
+// RightAlign returns the bits as a big-endian number, i.e. with the padding
+// moved to the most significant bits of the first octet.
+func (b BitString) RightAlign() []byte {
+	shift := uint(8 - b.BitLength%8)
+	if shift == 8 || len(b.Bytes) == 0 {
+		return b.Bytes
+	}
+
+	a := make([]byte, len(b.Bytes))
+	a[0] = b.Bytes[0] >> shift
+	for i := 1; i < len(b.Bytes); i++ {
+		a[i] = b.Bytes[i-1] << (8 - shift)
+		a[i] |= b.Bytes[i] >> shift
+	}
+	return a
+}
+
+// NewBitString returns a BIT STRING packet holding value, prefixed with the
+// number of unused bits in the last octet. It panics if the length of
+// value.Bytes does not match value.BitLength.
+func NewBitString(classType Class, tagType Type, tag Tag, value BitString, description string) *Packet {
+	if value.BitLength < 0 || len(value.Bytes) != (value.BitLength+7)/8 {
+		panic(fmt.Sprintf("ber: BIT STRING of %d bits can't be held by %d octets", value.BitLength, len(value.Bytes)))
+	}
+	p := Encode(classType, tagType, tag, nil, description)
+
+	p.Value = value
+	p.Data.WriteByte(byte(len(value.Bytes)*8 - value.BitLength))
+	p.Data.Write(value.Bytes)
+
+	return p
+}
+
+// ParseBitString parses the content octets of a primitive BIT STRING. The
+// returned Bytes share memory with v.
+func ParseBitString(v []byte) (BitString, error) {
+	if len(v) == 0 {
+		return BitString{}, errors.New("zero length BIT STRING")
+	}
+	unused := int(v[0])
+	if unused > 7 {
+		return BitString{}, fmt.Errorf("invalid number of unused bits %d in BIT STRING", unused)
+	}
+	if len(v) == 1 && unused != 0 {
+		return BitString{}, errors.New("unused bits in empty BIT STRING")
+	}
+	return BitString{Bytes: v[1:], BitLength: (len(v)-1)*8 - unused}, nil
+}
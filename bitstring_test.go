@@ -0,0 +1,80 @@
+package ber
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBooleanFlags(t *testing.T) {
+	flags := []bool{true, false, true, true, false, false, false, true, false, true}
+
+	p := NewBooleanFlags("flags", flags)
+	expected := []byte{0x03, 0x03, 0x06, 0xB1, 0x40}
+	if !bytes.Equal(expected, p.Bytes()) {
+		t.Errorf("unexpected encoding\nwant: % X\ngot:  % X", expected, p.Bytes())
+	}
+
+	decoded, err := DecodePacketErr(p.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := ParseBooleanFlags(decoded.Data.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error parsing flags: %v", err)
+	}
+	if !reflect.DeepEqual(flags, got) {
+		t.Errorf("expected %v, got %v", flags, got)
+	}
+	if !p.Equal(decoded) {
+		t.Errorf("expected the built packet to equal the decoded one, got %#v and %#v", p.Value, decoded.Value)
+	}
+
+	for _, invalid := range [][]byte{{}, {0x08, 0x00}, {0x01}} {
+		if _, err := ParseBooleanFlags(invalid); err == nil {
+			t.Errorf("% X: expected error", invalid)
+		}
+	}
+}
+
+func TestBitString(t *testing.T) {
+	// x.690, 8.6.4.2
+	data := []byte{0x03, 0x04, 0x06, 0x6E, 0x5D, 0xC0}
+
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, ok := p.Value.(BitString)
+	if !ok {
+		t.Fatalf("expected BitString value, got %T", p.Value)
+	}
+	if b.BitLength != 18 || !bytes.Equal([]byte{0x6E, 0x5D, 0xC0}, b.Bytes) {
+		t.Errorf("unexpected bit string %d bits % X", b.BitLength, b.Bytes)
+	}
+
+	bits := "011011100101110111"
+	for i, c := range bits {
+		if b.At(i) != (c == '1') {
+			t.Errorf("bit %d: expected %c", i, c)
+		}
+	}
+	if b.At(-1) || b.At(18) {
+		t.Error("expected false for bits out of range")
+	}
+	if aligned := b.RightAlign(); !bytes.Equal([]byte{0x01, 0xB9, 0x77}, aligned) {
+		t.Errorf("unexpected right aligned bits % X", aligned)
+	}
+
+	encoded := NewBitString(ClassUniversal, TypePrimitive, TagBitString, b, "")
+	if !bytes.Equal(data, encoded.Bytes()) {
+		t.Errorf("expected % X, got % X", data, encoded.Bytes())
+	}
+
+	if _, err := ParseBitString([]byte{0x08, 0x00}); err == nil {
+		t.Error("expected error for invalid number of unused bits")
+	}
+	if p := DecodePacket([]byte{0x03, 0x00}); p == nil || p.Value != nil {
+		t.Error("expected invalid BIT STRING to decode without a value")
+	}
+}
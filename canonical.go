@@ -0,0 +1,265 @@
+package ber
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// CanonicalBytes returns a DER-canonical encoding of the tree, suitable as a
+// map or cache key. Unlike Bytes, which preserves the encoding choices made
+// when the tree was built, two semantically equal trees produce the same
+// canonical bytes, regardless of how they were built or decoded:
+//
+//   - every length is encoded in its definite, minimal form
+//   - BOOLEAN true is encoded as 0xFF
+//   - INTEGER and ENUMERATED values are encoded in the minimal number of octets
+//   - REAL values are encoded in base 2 with an odd mantissa (x.690, 11.3.1)
+//   - the unused bits of a BIT STRING are set to zero
+//   - constructed BIT STRING, OCTET STRING and character strings are
+//     flattened into their primitive form
+//   - the elements of a SET are sorted by their encoding
+//
+// Content that fails to decode, and the content of non-universal primitives,
+// is kept as it is.
+func (p *Packet) CanonicalBytes() []byte {
+	var out bytes.Buffer
+	p.writeCanonical(&out)
+	return out.Bytes()
+}
+
+// DecodeWithFingerprint decodes the given bytes into a single Packet and
+// returns it together with the SHA-256 of its canonical encoding, so that
+// encodings of the same value produce the same fingerprint.
+func DecodeWithFingerprint(data []byte) (*Packet, [32]byte, error) {
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+	return p, sha256.Sum256(p.CanonicalBytes()), nil
+}
+
+func (p *Packet) writeCanonical(out *bytes.Buffer) {
+	identifier := p.Identifier
+	var content []byte
+	switch {
+	case p.TagType == TypeConstructed && p.ClassType == ClassUniversal && isStringTag(p.Tag) && (len(p.Children) == 0 || p.validateStringFragments() == nil):
+		identifier.TagType = TypePrimitive
+		content = canonicalPrimitiveContent(p.Tag, p.constructedStringContent())
+	case p.TagType == TypeConstructed:
+		children := make([][]byte, len(p.Children))
+		for i, child := range p.Children {
+			children[i] = child.CanonicalBytes()
+		}
+		if p.ClassType == ClassUniversal && p.Tag == TagSet {
+			sort.Slice(children, func(i, j int) bool {
+				return bytes.Compare(children[i], children[j]) < 0
+			})
+		}
+		content = bytes.Join(children, nil)
+	case p.ClassType == ClassUniversal:
+		content = canonicalPrimitiveContent(p.Tag, p.Data.Bytes())
+	default:
+		content = p.Data.Bytes()
+	}
+	out.Write(encodeIdentifier(identifier))
+	out.Write(encodeLength(len(content)))
+	out.Write(content)
+}
+
+// canonicalPrimitiveContent re-encodes the content octets of a universal
+// primitive from its decoded value, returning content unchanged if the value
+// has no other DER form or can't be decoded.
+func canonicalPrimitiveContent(tag Tag, content []byte) []byte {
+	switch tag {
+	case TagBoolean:
+		for _, b := range content {
+			if b != 0 {
+				return []byte{0xFF}
+			}
+		}
+		return []byte{0x00}
+	case TagInteger, TagEnumerated:
+		if v, err := ParseBigInt(content); err == nil {
+			return encodeBigInt(v)
+		}
+	case TagRealFloat:
+		if v, err := ParseReal(content); err == nil {
+			return encodeFloatDER(v)
+		}
+	case TagBitString:
+		if len(content) == 1 {
+			return []byte{0x00}
+		}
+		if len(content) > 1 && content[0] < 8 {
+			out := append([]byte(nil), content...)
+			out[len(out)-1] &^= 1<<content[0] - 1
+			return out
+		}
+	}
+	return content
+}
+
+// constructedStringContent concatenates the contents of the fragments of a
+// constructed string. For a BIT STRING, only the unused bits octet of the last
+// fragment is kept (x.690, 8.6.4).
+func (p *Packet) constructedStringContent() []byte {
+	if p.Tag == TagBitString {
+		bits, unused := p.bitStringFragments()
+		return append([]byte{unused}, bits...)
+	}
+	var content []byte
+	for _, child := range p.Children {
+		if child.TagType == TypeConstructed {
+			content = append(content, child.constructedStringContent()...)
+			continue
+		}
+		content = append(content, child.Data.Bytes()...)
+	}
+	return content
+}
+
+func (p *Packet) bitStringFragments() (bits []byte, unused byte) {
+	for _, child := range p.Children {
+		if child.TagType == TypeConstructed {
+			b, u := child.bitStringFragments()
+			bits, unused = append(bits, b...), u
+			continue
+		}
+		data := child.Data.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		bits, unused = append(bits, data[1:]...), data[0]
+	}
+	return bits, unused
+}
+
+// ReassembleConstructedStrings replaces every universal string in the tree
+// that uses the constructed encoding, e.g. an indefinite-length OCTET STRING,
+// with its primitive form holding the concatenated content and the decoded
+// Value. For a BIT STRING, only the last fragment may have unused bits, which
+// become the unused bits of the reassembled value (x.690, 8.6.4).
+func (p *Packet) ReassembleConstructedStrings() error {
+	if p.TagType != TypeConstructed {
+		return nil
+	}
+	if p.ClassType == ClassUniversal && isStringTag(p.Tag) {
+		if err := p.validateStringFragments(); err != nil {
+			return err
+		}
+		content := p.constructedStringContent()
+		p.TagType = TypePrimitive
+		p.Children = p.Children[:0]
+		p.Data.Reset()
+		p.Data.Write(content)
+		p.ByteValue = content
+		p.indefinite = false
+		return (&decoder{}).decodeUniversalValue(p, content)
+	}
+
+	changed := false
+	for _, child := range p.Children {
+		if child.TagType != TypeConstructed {
+			continue
+		}
+		if err := child.ReassembleConstructedStrings(); err != nil {
+			return err
+		}
+		changed = true
+	}
+	if changed {
+		p.Data.Reset()
+		for _, child := range p.Children {
+			p.Data.Write(child.Bytes())
+		}
+	}
+	return nil
+}
+
+// reassembleString sets the Value of the decoded constructed universal string
+// p as if it had been decoded in its primitive form. The concatenated content
+// is charged to MaxTotalAllocation. Like for primitive BIT STRINGs, malformed
+// fragments leave the Value unset rather than failing the decode.
+func (d *decoder) reassembleString(p *Packet) error {
+	if err := p.validateStringFragments(); err != nil {
+		return nil
+	}
+	if err := d.allocate(p.constructedStringLength()); err != nil {
+		return err
+	}
+	tmp := &Packet{Identifier: Identifier{ClassType: ClassUniversal, TagType: TypePrimitive, Tag: p.Tag}}
+	if err := d.decodeUniversalValue(tmp, p.constructedStringContent()); err == nil {
+		p.Value = tmp.Value
+	}
+	return nil
+}
+
+// constructedStringLength returns the length of constructedStringContent
+// without concatenating the fragments.
+func (p *Packet) constructedStringLength() int {
+	n := 0
+	for _, f := range p.stringFragments(nil) {
+		n += f.Data.Len()
+		if p.Tag == TagBitString && f.Data.Len() > 0 {
+			n-- // unused bits octet
+		}
+	}
+	if p.Tag == TagBitString {
+		n++
+	}
+	return n
+}
+
+// validateStringFragments checks that the fragments of a constructed string
+// are strings of the same type and, for a BIT STRING, that only the last
+// fragment has unused bits.
+func (p *Packet) validateStringFragments() error {
+	fragments := p.stringFragments(nil)
+	for i, f := range fragments {
+		if f.ClassType != ClassUniversal || f.Tag != p.Tag {
+			return fmt.Errorf("fragment %d of constructed %s has tag %d", i, tagMap[p.Tag], f.Tag)
+		}
+		if p.Tag != TagBitString {
+			continue
+		}
+		data := f.Data.Bytes()
+		if len(data) == 0 {
+			return fmt.Errorf("fragment %d of constructed BIT STRING has no unused bits octet", i)
+		}
+		if data[0] > 7 || (data[0] != 0 && (i != len(fragments)-1 || len(data) == 1)) {
+			return fmt.Errorf("invalid number of unused bits %d in fragment %d of constructed BIT STRING", data[0], i)
+		}
+	}
+	if p.Tag == TagBitString && len(fragments) == 0 {
+		return errors.New("constructed BIT STRING without fragments")
+	}
+	return nil
+}
+
+func (p *Packet) stringFragments(fragments []*Packet) []*Packet {
+	for _, child := range p.Children {
+		if child.TagType == TypeConstructed && child.ClassType == ClassUniversal && child.Tag == p.Tag {
+			fragments = child.stringFragments(fragments)
+			continue
+		}
+		fragments = append(fragments, child)
+	}
+	return fragments
+}
+
+// isStringTag reports whether the universal tag is a string type that may use
+// the constructed encoding (x.690, 8.6, 8.7 and 8.23).
+func isStringTag(tag Tag) bool {
+	switch tag {
+	case TagBitString, TagOctetString, TagObjectDescriptor, TagUTF8String,
+		TagNumericString, TagPrintableString, TagT61String, TagVideotexString,
+		TagIA5String, TagUTCTime, TagGeneralizedTime, TagGraphicString,
+		TagVisibleString, TagGeneralString, TagUniversalString,
+		TagCharacterString, TagBMPString:
+		return true
+	}
+	return false
+}
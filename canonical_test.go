@@ -0,0 +1,238 @@
+package ber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCanonicalBytes(t *testing.T) {
+	// Built with NewBoolean, encoding true as 0x01
+	built := NewSequence("built")
+	built.AppendChild(NewBoolean(ClassUniversal, TypePrimitive, TagBoolean, true, "flag"))
+	built.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "abcd", "value"))
+	set := Encode(ClassUniversal, TypeConstructed, TagSet, nil, "set")
+	set.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, 2, ""))
+	set.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, 1, ""))
+	built.AppendChild(set)
+
+	// Indefinite length, LDAP boolean, constructed OCTET STRING and a differently ordered SET
+	decoded, err := DecodePacketErr([]byte{
+		0x30, 0x80,
+		0x01, 0x01, 0xFF,
+		0x24, 0x80, 0x04, 0x02, 'a', 'b', 0x04, 0x02, 'c', 'd', 0x00, 0x00,
+		0x31, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02,
+		0x00, 0x00,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if bytes.Equal(built.Bytes(), decoded.Bytes()) {
+		t.Fatal("test trees should have different encodings")
+	}
+	if !bytes.Equal(built.CanonicalBytes(), decoded.CanonicalBytes()) {
+		t.Errorf("canonical encodings differ\n% X\n% X", built.CanonicalBytes(), decoded.CanonicalBytes())
+	}
+
+	expected := []byte{
+		0x30, 0x11,
+		0x01, 0x01, 0xFF,
+		0x04, 0x04, 'a', 'b', 'c', 'd',
+		0x31, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02,
+	}
+	if !bytes.Equal(expected, built.CanonicalBytes()) {
+		t.Errorf("unexpected canonical encoding\nwant: % X\ngot:  % X", expected, built.CanonicalBytes())
+	}
+}
+
+func TestCanonicalBytesBitString(t *testing.T) {
+	// Two fragments: 0x6E 0x5D (no unused bits) and 0xC0 with 6 unused bits
+	decoded, err := DecodePacketErr([]byte{
+		0x23, 0x09,
+		0x03, 0x03, 0x00, 0x6E, 0x5D,
+		0x03, 0x02, 0x06, 0xC0,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []byte{0x03, 0x04, 0x06, 0x6E, 0x5D, 0xC0}
+	if !bytes.Equal(expected, decoded.CanonicalBytes()) {
+		t.Errorf("unexpected canonical encoding\nwant: % X\ngot:  % X", expected, decoded.CanonicalBytes())
+	}
+}
+
+func TestCanonicalBytesPrimitives(t *testing.T) {
+	decode := func(data []byte) *Packet {
+		p, err := DecodePacketErr(data)
+		if err != nil {
+			t.Fatalf("% X: unexpected error: %v", data, err)
+		}
+		return p
+	}
+
+	for _, tc := range []struct {
+		name     string
+		p        *Packet
+		expected []byte
+	}{
+		{"padded INTEGER", NewIntegerWidth(ClassUniversal, TypePrimitive, TagInteger, 5, 4, ""), []byte{0x02, 0x01, 0x05}},
+		{"padded negative INTEGER", decode([]byte{0x02, 0x03, 0xFF, 0xFF, 0x80}), []byte{0x02, 0x01, 0x80}},
+		{"padded ENUMERATED", decode([]byte{0x0a, 0x02, 0x00, 0x01}), []byte{0x0a, 0x01, 0x01}},
+		{"decimal REAL", NewReal(ClassUniversal, TypePrimitive, TagRealFloat, 0.5, ""), []byte{0x09, 0x03, 0x80, 0xFF, 0x01}},
+		{"base 16 REAL", decode([]byte{0x09, 0x03, 0xA0, 0xFF, 0x08}), []byte{0x09, 0x03, 0x80, 0xFF, 0x01}},
+		{"negative REAL", NewReal(ClassUniversal, TypePrimitive, TagRealFloat, -3.0, ""), []byte{0x09, 0x03, 0xC0, 0x00, 0x03}},
+		{"REAL zero", NewReal(ClassUniversal, TypePrimitive, TagRealFloat, 0.0, ""), []byte{0x09, 0x00}},
+		{"BIT STRING unused bits", decode([]byte{0x03, 0x02, 0x04, 0xFF}), []byte{0x03, 0x02, 0x04, 0xF0}},
+		{"empty constructed OCTET STRING", decode([]byte{0x24, 0x80, 0x00, 0x00}), []byte{0x04, 0x00}},
+		{"empty constructed BIT STRING", decode([]byte{0x23, 0x00}), []byte{0x03, 0x01, 0x00}},
+		{"indefinite context constructed", decode([]byte{0xa0, 0x80, 0x00, 0x00}), []byte{0xa0, 0x00}},
+		{"indefinite SEQUENCE", decode([]byte{0x30, 0x80, 0x02, 0x02, 0x00, 0x01, 0x00, 0x00}), []byte{0x30, 0x03, 0x02, 0x01, 0x01}},
+	} {
+		if got := tc.p.CanonicalBytes(); !bytes.Equal(tc.expected, got) {
+			t.Errorf("%s: expected % X, got % X", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestDecodeWithFingerprint(t *testing.T) {
+	definite := []byte{0x30, 0x06, 0x01, 0x01, 0x01, 0x02, 0x01, 0x05}
+	indefinite := []byte{0x30, 0x80, 0x01, 0x01, 0xFF, 0x02, 0x01, 0x05, 0x00, 0x00}
+	different := []byte{0x30, 0x06, 0x01, 0x01, 0x01, 0x02, 0x01, 0x06}
+
+	_, a, err := DecodeWithFingerprint(definite)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, b, err := DecodeWithFingerprint(indefinite)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, c, err := DecodeWithFingerprint(different)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if a != b {
+		t.Errorf("expected identical fingerprints for equal values, got %x and %x", a, b)
+	}
+	if a == c {
+		t.Error("expected different fingerprints for different values")
+	}
+
+	if _, _, err := DecodeWithFingerprint([]byte{0x30, 0x06}); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
+func TestReassembleConstructedStrings(t *testing.T) {
+	// SEQUENCE { BIT STRING (constructed, indefinite) { '011011100101110111'B in two fragments },
+	//            OCTET STRING (constructed) { "ab", "c" } }
+	data := []byte{
+		0x30, 0x16,
+		0x23, 0x80, 0x03, 0x03, 0x00, 0x6e, 0x5d, 0x03, 0x02, 0x06, 0xc0, 0x00, 0x00,
+		0x24, 0x07, 0x04, 0x02, 'a', 'b', 0x04, 0x01, 'c',
+	}
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.ReassembleConstructedStrings(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bits, ok := p.Children[0].Value.(BitString)
+	if !ok || p.Children[0].TagType != TypePrimitive {
+		t.Fatalf("expected primitive BIT STRING, got %s", DescribePacket(p.Children[0]))
+	}
+	if bits.BitLength != 18 || !bytes.Equal([]byte{0x6e, 0x5d, 0xc0}, bits.Bytes) {
+		t.Errorf("expected 18 bits 6E 5D C0, got %d bits % X", bits.BitLength, bits.Bytes)
+	}
+	if p.Children[1].Value != "abc" {
+		t.Errorf("expected OCTET STRING abc, got %v", p.Children[1].Value)
+	}
+
+	expected := []byte{0x30, 0x0b, 0x03, 0x04, 0x06, 0x6e, 0x5d, 0xc0, 0x04, 0x03, 'a', 'b', 'c'}
+	if !bytes.Equal(expected, p.Bytes()) {
+		t.Errorf("expected % X, got % X", expected, p.Bytes())
+	}
+	if p.UsedIndefiniteLength() {
+		t.Error("expected no indefinite length after reassembly")
+	}
+
+	for _, invalid := range [][]byte{
+		// unused bits in the first fragment
+		{0x23, 0x08, 0x03, 0x02, 0x04, 0x60, 0x03, 0x02, 0x00, 0xc0},
+		// INTEGER fragment in an OCTET STRING
+		{0x24, 0x03, 0x02, 0x01, 0x01},
+	} {
+		p, err := DecodePacketErr(invalid)
+		if err != nil {
+			t.Fatalf("% X: unexpected error decoding: %v", invalid, err)
+		}
+		if err := p.ReassembleConstructedStrings(); err == nil {
+			t.Errorf("% X: expected error", invalid)
+		}
+	}
+}
+
+func TestDecodeConstructedStringValue(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		data     []byte
+		expected interface{}
+	}{
+		{"two fragments", []byte{0x24, 0x07, 0x04, 0x02, 'a', 'b', 0x04, 0x01, 'c'}, "abc"},
+		{"nested fragment", []byte{0x24, 0x80, 0x24, 0x04, 0x04, 0x02, 'a', 'b', 0x04, 0x01, 'c', 0x00, 0x00}, "abc"},
+		{"utf8 fragments", []byte{0x2c, 0x09, 0x0c, 0x02, 'C', 'a', 0x0c, 0x03, 'f', 0xc3, 0xa9}, "Café"},
+		{"non-string fragment", []byte{0x24, 0x03, 0x02, 0x01, 0x01}, nil},
+	} {
+		p, err := DecodePacketErr(tc.data)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if p.Value != tc.expected {
+			t.Errorf("%s: expected %#v, got %#v", tc.name, tc.expected, p.Value)
+		}
+	}
+}
+
+func TestDecodeNestedConstructedString(t *testing.T) {
+	// 999 nested indefinite-length OCTET STRINGs around a 100 KB fragment
+	payload := bytes.Repeat([]byte{'x'}, 100000)
+	var data []byte
+	for i := 0; i < 999; i++ {
+		data = append(data, 0x24, 0x80)
+	}
+	data = append(data, 0x04, 0x83, 0x01, 0x86, 0xA0)
+	data = append(data, payload...)
+	for i := 0; i < 999; i++ {
+		data = append(data, 0x00, 0x00)
+	}
+
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, ok := p.Value.(string); !ok || v != string(payload) {
+		t.Errorf("expected the reassembled payload, got %T of length %d", p.Value, len(v))
+	}
+}
+
+func TestDecodeConstructedStringAllocation(t *testing.T) {
+	// OCTET STRING { "abc", "def" }: 6 content octets, 10 octets of child
+	// encodings held by the parent and 6 octets of reassembled value
+	data := []byte{0x24, 0x0a, 0x04, 0x03, 'a', 'b', 'c', 0x04, 0x03, 'd', 'e', 'f'}
+
+	if _, err := DecodePacketOptions(data, DecodeOptions{MaxTotalAllocation: 21}); err == nil {
+		t.Error("expected the reassembled value to be charged to MaxTotalAllocation")
+	}
+	p, err := DecodePacketOptions(data, DecodeOptions{MaxTotalAllocation: 22})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Value != "abcdef" {
+		t.Errorf("expected abcdef, got %v", p.Value)
+	}
+}
@@ -1,5 +1,225 @@
 package ber
 
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+func encodeInteger(i int64) []byte {
+	return minimalTwosComplement(i)
+}
+
+// minimalTwosComplement returns the big-endian two's complement encoding of v
+// using the minimum number of octets (x.690, 8.3.2). It is shared by all
+// signed integer encodings.
+func minimalTwosComplement(v int64) []byte {
+	n := int64Length(v)
+	out := make([]byte, n)
+
+	var j int
+	for ; n > 0; n-- {
+		out[j] = byte(v >> uint((n-1)*8))
+		j++
+	}
+
+	return out
+}
+
+// NewIntegerWidth returns an INTEGER packet holding value encoded in exactly
+// width content octets, sign extending as needed. This allows reproducing
+// non-minimal encodings byte for byte, e.g. from a wire capture. It panics if
+// width is less than the minimal length of value.
+func NewIntegerWidth(classType Class, tagType Type, tag Tag, value int64, width int, description string) *Packet {
+	content := minimalTwosComplement(value)
+	if width < len(content) {
+		panic(fmt.Sprintf("ber: integer %d needs at least %d octets, got width %d", value, len(content), width))
+	}
+	p := Encode(classType, tagType, tag, nil, description)
+
+	p.Value = value
+	pad := byte(0x00)
+	if value < 0 {
+		pad = 0xFF
+	}
+	for i := len(content); i < width; i++ {
+		p.Data.WriteByte(pad)
+	}
+	p.Data.Write(content)
+
+	return p
+}
+
+func int64Length(i int64) (numBytes int) {
+	numBytes = 1
+
+	for i > 127 {
+		numBytes++
+		i >>= 8
+	}
+
+	for i < -128 {
+		numBytes++
+		i >>= 8
+	}
+
+	return
+}
+
+// ParseInt64Strict is like ParseInt64, but rejects encodings that are not
+// minimal as required by DER (x.690, 8.3.2), i.e. a leading 0x00 followed by
+// an octet with the high bit clear or a leading 0xFF followed by an octet
+// with the high bit set.
+func ParseInt64Strict(bytes []byte) (int64, error) {
+	if err := validateIntegerDER(bytes); err != nil {
+		return 0, err
+	}
+	return ParseInt64(bytes)
+}
+
+func validateIntegerDER(content []byte) error {
+	if len(content) == 0 {
+		return errors.New("integer must contain at least one octet")
+	}
+	if len(content) > 1 {
+		if content[0] == 0x00 && content[1]&0x80 == 0 {
+			return errors.New("integer not minimally encoded: redundant leading 0x00")
+		}
+		if content[0] == 0xFF && content[1]&0x80 != 0 {
+			return errors.New("integer not minimally encoded: redundant leading 0xFF")
+		}
+	}
+	return nil
+}
+
+// ParseBigInt parses the content of an INTEGER of arbitrary size as a
+// two's complement number. Unlike ParseInt64 it does not fail for content
+// longer than 8 bytes, e.g. certificate serial numbers or SNMP counters.
+func ParseBigInt(bytes []byte) (*big.Int, error) {
+	if len(bytes) == 0 {
+		return nil, errors.New("integer must contain at least one octet")
+	}
+	ret := new(big.Int).SetBytes(bytes)
+	if bytes[0]&0x80 != 0 {
+		// Negative, subtract 2^(8*len) to sign extend the result.
+		ret.Sub(ret, new(big.Int).Lsh(big.NewInt(1), uint(len(bytes))*8))
+	}
+	return ret, nil
+}
+
+// parseIntegerValue returns the Value of a decoded INTEGER, an int64 unless
+// the content is too long for it, in which case a *big.Int is returned.
+func parseIntegerValue(content []byte) interface{} {
+	if len(content) > 8 {
+		if v, err := ParseBigInt(content); err == nil {
+			return v
+		}
+	}
+	v, _ := ParseInt64(content)
+	return v
+}
+
+// bigIntValue converts the integer Value of a packet to a *big.Int.
+func bigIntValue(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, v != nil
+	case int:
+		return big.NewInt(int64(v)), true
+	case int64:
+		return big.NewInt(v), true
+	case int32:
+		return big.NewInt(int64(v)), true
+	case int16:
+		return big.NewInt(int64(v)), true
+	case int8:
+		return big.NewInt(int64(v)), true
+	case uint:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint64:
+		return new(big.Int).SetUint64(v), true
+	case uint32:
+		return big.NewInt(int64(v)), true
+	case uint16:
+		return big.NewInt(int64(v)), true
+	case uint8:
+		return big.NewInt(int64(v)), true
+	}
+	return nil, false
+}
+
+// NewBigInt returns an INTEGER packet holding the minimal two's complement
+// encoding of value. The Value of the packet is set to value.
+func NewBigInt(classType Class, tagType Type, tag Tag, value *big.Int, description string) *Packet {
+	if value == nil {
+		panic("ber: NewBigInt called with a nil value")
+	}
+	p := Encode(classType, tagType, tag, nil, description)
+
+	p.Value = value
+	p.Data.Write(encodeBigInt(value))
+
+	return p
+}
+
+func encodeBigInt(v *big.Int) []byte {
+	if v.Sign() < 0 {
+		// The two's complement of v is the bitwise inverse of -v-1.
+		n := new(big.Int).Neg(v)
+		n.Sub(n, big.NewInt(1))
+		out := n.Bytes()
+		for i := range out {
+			out[i] ^= 0xFF
+		}
+		if len(out) == 0 || out[0]&0x80 == 0 {
+			out = append([]byte{0xFF}, out...)
+		}
+		return out
+	}
+
+	out := v.Bytes()
+	if len(out) == 0 || out[0]&0x80 != 0 {
+		out = append([]byte{0x00}, out...)
+	}
+	return out
+}
+
+// ParseUint64 parses the content of an INTEGER as an unsigned big-endian
+// number, as used by e.g. SNMP Gauge32 and Counter64, where the high bit is
+// data rather than a sign. Leading zero octets are ignored, more than 8
+// significant octets are rejected.
+func ParseUint64(bytes []byte) (ret uint64, err error) {
+	for len(bytes) > 1 && bytes[0] == 0x00 {
+		bytes = bytes[1:]
+	}
+	if len(bytes) > 8 {
+		// We'll overflow an uint64 in this case.
+		err = fmt.Errorf("integer too large")
+		return
+	}
+	for bytesRead := 0; bytesRead < len(bytes); bytesRead++ {
+		ret <<= 8
+		ret |= uint64(bytes[bytesRead])
+	}
+	return
+}
+
+// NewUint64 returns an INTEGER packet holding value. A leading zero octet is
+// only added when the high bit of the value would otherwise be read as sign.
+func NewUint64(classType Class, tagType Type, tag Tag, value uint64, description string) *Packet {
+	p := Encode(classType, tagType, tag, nil, description)
+
+	p.Value = value
+	content := encodeUnsignedInteger(value)
+	if content[0]&0x80 != 0 {
+		p.Data.WriteByte(0x00)
+	}
+	p.Data.Write(content)
+
+	return p
+}
+
 func encodeUnsignedInteger(i uint64) []byte {
 	n := uint64Length(i)
 	out := make([]byte, n)
@@ -23,3 +243,40 @@ func uint64Length(i uint64) (numBytes int) {
 
 	return
 }
+
+// AsDuration interprets the content of an integer packet as a number of units,
+// e.g. SNMP TimeTicks in hundredths of a second, and returns the corresponding
+// duration. Application and context tagged integers are decoded from their
+// content as well.
+func (p *Packet) AsDuration(unit time.Duration) (time.Duration, error) {
+	if p.TagType != TypePrimitive {
+		return 0, errors.New("duration must be a primitive integer")
+	}
+	v, err := ParseInt64(p.Data.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	d := time.Duration(v) * unit
+	if unit != 0 && d/unit != time.Duration(v) {
+		return 0, fmt.Errorf("duration of %d * %s overflows", v, unit)
+	}
+	return d, nil
+}
+
+// EnumName returns the name of the enumerated value of p in names, e.g. for
+// dumping LDAP result codes. Application and context tagged values are
+// decoded from their content. Values without a name are returned as
+// "Enumerated(<value>)".
+func (p *Packet) EnumName(names map[int64]string) string {
+	v, ok := p.Value.(int64)
+	if !ok {
+		var err error
+		if v, err = ParseInt64(p.Data.Bytes()); err != nil || p.TagType != TypePrimitive {
+			return fmt.Sprintf("Enumerated(% X)", p.Data.Bytes())
+		}
+	}
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("Enumerated(%d)", v)
+}
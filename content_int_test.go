@@ -0,0 +1,325 @@
+package ber
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestMinimalTwosComplement(t *testing.T) {
+	for _, tc := range []struct {
+		v int64
+		e []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{-1, []byte{0xFF}},
+		{127, []byte{0x7F}},
+		{128, []byte{0x00, 0x80}},
+		{-128, []byte{0x80}},
+		{-129, []byte{0xFF, 0x7F}},
+		{255, []byte{0x00, 0xFF}},
+		{256, []byte{0x01, 0x00}},
+		{32767, []byte{0x7F, 0xFF}},
+		{32768, []byte{0x00, 0x80, 0x00}},
+		{-32768, []byte{0x80, 0x00}},
+		{-32769, []byte{0xFF, 0x7F, 0xFF}},
+		{math.MaxInt32, []byte{0x7F, 0xFF, 0xFF, 0xFF}},
+		{math.MinInt32, []byte{0x80, 0x00, 0x00, 0x00}},
+		{math.MaxInt64, []byte{0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{math.MinInt64, []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+	} {
+		b := minimalTwosComplement(tc.v)
+		if !bytes.Equal(tc.e, b) {
+			t.Errorf("%d: expected % X, got % X", tc.v, tc.e, b)
+		}
+		if dec, err := ParseInt64(b); err != nil || dec != tc.v {
+			t.Errorf("%d: decoded as %d (%v)", tc.v, dec, err)
+		}
+	}
+}
+
+func TestInt64LengthNegative(t *testing.T) {
+	for _, tc := range []struct {
+		v int64
+		n int
+	}{
+		{-1, 1},
+		{-128, 1},
+		{-129, 2},
+		{-32768, 2},
+		{-32769, 3},
+		{math.MinInt64, 8},
+	} {
+		if n := int64Length(tc.v); n != tc.n {
+			t.Errorf("%d: expected %d octets, got %d", tc.v, tc.n, n)
+		}
+	}
+
+	// -2^(8n-1) is the smallest value fitting n octets, one less needs n+1
+	for n := 1; n < 8; n++ {
+		lowest := int64(-1) << uint(8*n-1)
+		for _, v := range []int64{lowest, lowest - 1, lowest + 1} {
+			b := encodeInteger(v)
+			if err := validateIntegerDER(b); err != nil {
+				t.Errorf("%d: encoding % X is not minimal: %v", v, b, err)
+			}
+			expected := n
+			if v < lowest {
+				expected = n + 1
+			}
+			if len(b) != expected {
+				t.Errorf("%d: expected %d octets, got % X", v, expected, b)
+			}
+			if dec, err := ParseInt64(b); err != nil || dec != v {
+				t.Errorf("%d: decoded as %d (%v)", v, dec, err)
+			}
+		}
+	}
+}
+
+func TestEnumerated(t *testing.T) {
+	p := NewEnumerated(ClassUniversal, TypePrimitive, TagEnumerated, 32, "resultCode")
+	if !bytes.Equal([]byte{0x0a, 0x01, 0x20}, p.Bytes()) {
+		t.Errorf("unexpected encoding % X", p.Bytes())
+	}
+
+	decoded := DecodePacket(p.Bytes())
+	if v, ok := decoded.Value.(int64); !ok || v != 32 {
+		t.Errorf("expected 32, got %v", decoded.Value)
+	}
+}
+
+func TestAsDuration(t *testing.T) {
+	// sysUpTime as SNMP TimeTicks ([APPLICATION 3]), 123456 hundredths of a second
+	data := []byte{0x43, 0x03, 0x01, 0xE2, 0x40}
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d, err := p.AsDuration(10 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := 1234*time.Second + 560*time.Millisecond; d != expected {
+		t.Errorf("expected %s, got %s", expected, d)
+	}
+
+	if _, err := Integer(math.MaxInt64).AsDuration(time.Hour); err == nil {
+		t.Error("expected overflow error")
+	}
+	if _, err := NewSequence("").AsDuration(time.Second); err == nil {
+		t.Error("expected error for a constructed packet")
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	// 20 byte negative certificate serial number
+	serial, _ := new(big.Int).SetString("-7fedcba9876543210fedcba9876543210fedcba9", 16)
+	expected := []byte{0x02, 0x14,
+		0x80, 0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0, 0x12,
+		0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0, 0x12, 0x34, 0x57}
+
+	p := NewBigInt(ClassUniversal, TypePrimitive, TagInteger, serial, "serialNumber")
+	if !bytes.Equal(expected, p.Bytes()) {
+		t.Fatalf("unexpected encoding % X", p.Bytes())
+	}
+	decoded, err := DecodePacketErr(p.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := ParseBigInt(decoded.Data.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.Cmp(serial) != 0 {
+		t.Errorf("expected %s, got %s", serial, v)
+	}
+
+	for _, i := range []int64{0, 1, -1, 127, 128, -128, -129, 256, math.MaxInt64, math.MinInt64} {
+		b := encodeBigInt(big.NewInt(i))
+		if !bytes.Equal(encodeInteger(i), b) {
+			t.Errorf("%d: expected % X, got % X", i, encodeInteger(i), b)
+		}
+		if v, err := ParseBigInt(b); err != nil || v.Int64() != i {
+			t.Errorf("%d: decoded as %v (%v)", i, v, err)
+		}
+	}
+
+	if _, err := ParseBigInt(nil); err == nil {
+		t.Error("expected error for empty content")
+	}
+}
+
+func TestUint64(t *testing.T) {
+	for _, tc := range []struct {
+		v uint64
+		e []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7F}},
+		{128, []byte{0x00, 0x80}},
+		{0xFFFF, []byte{0x00, 0xFF, 0xFF}},
+		{math.MaxUint32, []byte{0x00, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{math.MaxUint64, []byte{0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+	} {
+		p := NewUint64(ClassApplication, TypePrimitive, 6, tc.v, "Counter64")
+		if !bytes.Equal(tc.e, p.Data.Bytes()) {
+			t.Errorf("%d: expected % X, got % X", tc.v, tc.e, p.Data.Bytes())
+		}
+		if v, err := ParseUint64(p.Data.Bytes()); err != nil || v != tc.v {
+			t.Errorf("%d: decoded as %d (%v)", tc.v, v, err)
+		}
+	}
+
+	if v, err := ParseUint64([]byte{0xFF, 0xFF, 0xFF, 0xFF}); err != nil || v != 4294967295 {
+		t.Errorf("expected 4294967295, got %d (%v)", v, err)
+	}
+	if _, err := ParseUint64([]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}); err == nil {
+		t.Error("expected error for more than 8 significant octets")
+	}
+}
+
+func TestNewIntegerWidth(t *testing.T) {
+	for _, tc := range []struct {
+		v     int64
+		width int
+		e     []byte
+	}{
+		{10, 4, []byte{0x02, 0x04, 0x00, 0x00, 0x00, 0x0A}},
+		{10, 1, []byte{0x02, 0x01, 0x0A}},
+		{-2, 3, []byte{0x02, 0x03, 0xFF, 0xFF, 0xFE}},
+		{128, 2, []byte{0x02, 0x02, 0x00, 0x80}},
+	} {
+		p := NewIntegerWidth(ClassUniversal, TypePrimitive, TagInteger, tc.v, tc.width, "")
+		if !bytes.Equal(tc.e, p.Bytes()) {
+			t.Errorf("%d/%d: expected % X, got % X", tc.v, tc.width, tc.e, p.Bytes())
+		}
+		if v, err := ParseInt64(p.Data.Bytes()); err != nil || v != tc.v {
+			t.Errorf("%d/%d: decoded as %d (%v)", tc.v, tc.width, v, err)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for a width below the minimal length")
+		}
+	}()
+	NewIntegerWidth(ClassUniversal, TypePrimitive, TagInteger, 128, 1, "")
+}
+
+func TestParseInt64Strict(t *testing.T) {
+	for _, tc := range []struct {
+		b     []byte
+		v     int64
+		valid bool
+	}{
+		{[]byte{0x00}, 0, true},
+		{[]byte{0x7F}, 127, true},
+		{[]byte{0x00, 0x80}, 128, true},
+		{[]byte{0xFF}, -1, true},
+		{[]byte{0xFF, 0x7F}, -129, true},
+		{[]byte{0x80, 0x00}, -32768, true},
+		{[]byte{0x00, 0x00, 0x7F}, 0, false},
+		{[]byte{0x00, 0x7F}, 0, false},
+		{[]byte{0xFF, 0x80}, 0, false},
+		{[]byte{0xFF, 0xFF}, 0, false},
+		{[]byte{}, 0, false},
+	} {
+		v, err := ParseInt64Strict(tc.b)
+		if tc.valid && (err != nil || v != tc.v) {
+			t.Errorf("% X: expected %d, got %d (%v)", tc.b, tc.v, v, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("% X: expected error", tc.b)
+		}
+	}
+
+	data := []byte{0x02, 0x03, 0x00, 0x00, 0x7F}
+	if _, err := DecodePacketOptions(data, DecodeOptions{Strict: true}); err == nil {
+		t.Error("expected strict decoding to reject a redundant leading octet")
+	}
+	if p, err := DecodePacketOptions(data, DecodeOptions{}); err != nil || p.Value != int64(127) {
+		t.Errorf("expected lenient decoding to accept a redundant leading octet, got %v (%v)", p, err)
+	}
+}
+
+func TestEqualBigInt(t *testing.T) {
+	// 2^255 + 1, 33 content octets
+	v := new(big.Int).Lsh(big.NewInt(1), 255)
+	v.Add(v, big.NewInt(1))
+	data := append([]byte{0x02, 0x21, 0x00, 0x80}, make([]byte, 31)...)
+	data[len(data)-1] = 0x01
+
+	decoded, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d, ok := decoded.Value.(*big.Int); !ok || d.Cmp(v) != 0 {
+		t.Fatalf("expected decoded value %s, got %v", v, decoded.Value)
+	}
+
+	built := NewBigInt(ClassUniversal, TypePrimitive, TagInteger, new(big.Int).Set(v), "")
+	if !built.Equal(decoded) || !decoded.Equal(built) {
+		t.Error("expected packets holding the same 256-bit value to be equal")
+	}
+	if built.Equal(NewBigInt(ClassUniversal, TypePrimitive, TagInteger, new(big.Int).Sub(v, big.NewInt(1)), "")) {
+		t.Error("expected different values to differ")
+	}
+
+	small := NewBigInt(ClassUniversal, TypePrimitive, TagInteger, big.NewInt(300), "")
+	for _, other := range []*Packet{
+		Integer(300),
+		NewInteger(ClassUniversal, TypePrimitive, TagInteger, uint16(300), ""),
+		NewIntegerWidth(ClassUniversal, TypePrimitive, TagInteger, 300, 4, ""),
+	} {
+		if !small.Equal(other) {
+			t.Errorf("expected %v (%T) to equal 300", other.Value, other.Value)
+		}
+	}
+	if small.Equal(NewInteger(ClassContext, TypePrimitive, TagInteger, 300, "")) {
+		t.Error("expected different identifiers to differ")
+	}
+
+	// unsigned values above math.MaxInt64 decode into a *big.Int
+	for _, p := range []*Packet{
+		NewInteger(ClassUniversal, TypePrimitive, TagInteger, uint64(math.MaxUint64), ""),
+		NewInteger(ClassUniversal, TypePrimitive, TagInteger, ^uint(0), ""),
+		NewUint64(ClassUniversal, TypePrimitive, TagInteger, math.MaxUint64, ""),
+	} {
+		decoded, err := DecodePacketErr(p.Bytes())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !p.Equal(decoded) || !decoded.Equal(p) {
+			t.Errorf("%T: expected % X to equal its decoded value %v", p.Value, p.Bytes(), decoded.Value)
+		}
+		if p.Equal(Integer(-1)) {
+			t.Errorf("%T: expected math.MaxUint64 to differ from -1", p.Value)
+		}
+	}
+}
+
+func TestEnumName(t *testing.T) {
+	resultCodes := map[int64]string{0: "success", 32: "noSuchObject", 49: "invalidCredentials"}
+
+	for _, tc := range []struct {
+		p        *Packet
+		expected string
+	}{
+		{Enumerated(0), "success"},
+		{Enumerated(49), "invalidCredentials"},
+		{Enumerated(4711), "Enumerated(4711)"},
+		{NewEnumerated(ClassContext, TypePrimitive, 1, 32, ""), "noSuchObject"},
+	} {
+		decoded, err := DecodePacketErr(tc.p.Bytes())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := decoded.EnumName(resultCodes); got != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package ber
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// corpusDir holds inputs that caused crashes or hangs in the past, e.g. found
+// by the fuzz targets. Each file holds the raw bytes of one input; add new
+// findings there to keep them as permanent regression tests.
+const corpusDir = "testdata/corpus"
+
+// loadCorpus returns the contents of every file in corpusDir, keyed by file name.
+func loadCorpus(tb testing.TB) map[string][]byte {
+	tb.Helper()
+
+	files, err := ioutil.ReadDir(corpusDir)
+	if err != nil {
+		tb.Fatalf("failed to read corpus directory %s: %v", corpusDir, err)
+	}
+
+	corpus := make(map[string][]byte, len(files))
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(corpusDir, file.Name()))
+		if err != nil {
+			tb.Fatalf("failed to read corpus file %s: %v", file.Name(), err)
+		}
+		corpus[file.Name()] = data
+	}
+	return corpus
+}
+
+func TestCorpusDecodePacket(t *testing.T) {
+	for name, data := range loadCorpus(t) {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s: DecodePacketErr panicked: %v", name, r)
+				}
+			}()
+			p, err := DecodePacketErr(data)
+			if p == nil && err == nil {
+				t.Errorf("%s: DecodePacketErr returned a nil packet and no error", name)
+			}
+		}()
+	}
+}
@@ -0,0 +1,183 @@
+package ber
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TLV is a single element returned by DecoderState.Next.
+type TLV struct {
+	Identifier
+	// Offset of the first identifier octet in the input
+	Offset int
+	// Depth of the element, 0 for the outermost element
+	Depth int
+	// Length of the content, LengthIndefinite for indefinite-length elements
+	Length int
+	// Content holds the content octets of a primitive element
+	Content []byte
+	// End is set when the constructed element at Depth was completed, either
+	// by an end-of-contents marker or by reaching its definite length. Only
+	// Offset and Depth are valid in this case.
+	End bool
+}
+
+// DecoderState decodes BER encoded data one TLV at a time, leaving control
+// over the traversal to the caller. This allows interleaving decoding with
+// other protocol logic, e.g. stopping after a header element.
+type DecoderState struct {
+	data   []byte
+	offset int
+	// ends holds for each open constructed element the offset its content
+	// ends at, or LengthIndefinite if it is terminated by an EOC marker.
+	ends []int
+}
+
+// NewDecoderState returns a DecoderState positioned at the start of data.
+func NewDecoderState(data []byte) *DecoderState {
+	return &DecoderState{data: data}
+}
+
+// Offset returns the offset of the next TLV in the input.
+func (s *DecoderState) Offset() int {
+	return s.offset
+}
+
+// Depth returns the number of constructed elements currently open.
+func (s *DecoderState) Depth() int {
+	return len(s.ends)
+}
+
+// PendingTerminators returns the number of open indefinite-length elements
+// still awaiting their end-of-contents marker.
+func (s *DecoderState) PendingTerminators() int {
+	n := 0
+	for _, end := range s.ends {
+		if end == LengthIndefinite {
+			n++
+		}
+	}
+	return n
+}
+
+// Next decodes the next TLV. Constructed elements are entered, their children
+// are returned by the following calls, followed by a TLV with End set. io.EOF
+// is returned once all input was consumed with no element left open.
+func (s *DecoderState) Next() (TLV, error) {
+	if n := len(s.ends); n > 0 && s.ends[n-1] != LengthIndefinite {
+		if s.offset == s.ends[n-1] {
+			s.ends = s.ends[:n-1]
+			return TLV{Offset: s.offset, Depth: n - 1, End: true}, nil
+		}
+	}
+	if s.offset == len(s.data) {
+		if len(s.ends) > 0 {
+			return TLV{}, io.ErrUnexpectedEOF
+		}
+		return TLV{}, io.EOF
+	}
+
+	identifier, length, read, err := readHeader(bytes.NewReader(s.data[s.offset:]))
+	if err != nil {
+		return TLV{}, unexpectedEOF(err)
+	}
+	tlv := TLV{Identifier: identifier, Offset: s.offset, Depth: len(s.ends), Length: length}
+	contentStart := s.offset + read
+
+	if length == 0 && identifier == (Identifier{ClassType: ClassUniversal, TagType: TypePrimitive, Tag: TagEOC}) {
+		n := len(s.ends)
+		if n == 0 || s.ends[n-1] != LengthIndefinite {
+			return TLV{}, errors.New("eoc not allowed outside of an indefinite-length element")
+		}
+		s.ends = s.ends[:n-1]
+		s.offset = contentStart
+		return TLV{Offset: tlv.Offset, Depth: n - 1, End: true}, nil
+	}
+
+	if identifier.TagType == TypePrimitive && length == LengthIndefinite {
+		return TLV{}, errors.New("indefinite length used with primitive type")
+	}
+
+	end := contentStart + length
+	if length != LengthIndefinite {
+		if end > len(s.data) {
+			return TLV{}, io.ErrUnexpectedEOF
+		}
+		if n := len(s.ends); n > 0 && s.ends[n-1] != LengthIndefinite && end > s.ends[n-1] {
+			return TLV{}, fmt.Errorf("element at offset %d exceeds its parent by %d bytes", tlv.Offset, end-s.ends[n-1])
+		}
+	}
+
+	if identifier.TagType == TypeConstructed {
+		if length == LengthIndefinite {
+			end = LengthIndefinite
+		}
+		s.ends = append(s.ends, end)
+		s.offset = contentStart
+		return tlv, nil
+	}
+
+	tlv.Content = s.data[contentStart:end]
+	s.offset = end
+	return tlv, nil
+}
+
+// Profile decodes the single packet at the start of data without building the
+// tree and reports its number of elements, the maximum nesting depth (1 for a
+// packet without children) and the number of bytes it occupies. It allows
+// judging untrusted input before decoding it in full.
+func Profile(data []byte) (nodes int, maxDepth int, totalBytes int, err error) {
+	s := NewDecoderState(data)
+	for {
+		tlv, err := s.Next()
+		if err != nil {
+			return nodes, maxDepth, s.Offset(), unexpectedEOF(err)
+		}
+		if !tlv.End {
+			nodes++
+			if tlv.Depth+1 > maxDepth {
+				maxDepth = tlv.Depth + 1
+			}
+		}
+		if s.Depth() == 0 {
+			return nodes, maxDepth, s.Offset(), nil
+		}
+	}
+}
+
+// SplitSequence returns the raw TLV of each child of the SEQUENCE at the
+// start of data, by scanning the lengths only without decoding any values,
+// e.g. to extract the exact bytes of the TBSCertificate of a certificate.
+// The returned slices share data.
+func SplitSequence(data []byte) ([][]byte, error) {
+	s := NewDecoderState(data)
+	tlv, err := s.Next()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if tlv.Identifier != (Identifier{ClassType: ClassUniversal, TagType: TypeConstructed, Tag: TagSequence}) {
+		return nil, fmt.Errorf("expected a SEQUENCE, got %s %s tag %d", ClassMap[tlv.ClassType], TypeMap[tlv.TagType], tlv.Tag)
+	}
+
+	var children [][]byte
+	for {
+		start := s.Offset()
+		tlv, err := s.Next()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if tlv.End {
+			return children, nil
+		}
+		// Skip the content of a constructed child
+		for s.Depth() > 1 {
+			if _, err := s.Next(); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+		}
+		end := s.Offset()
+		children = append(children, data[start:end:end])
+	}
+}
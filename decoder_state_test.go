@@ -0,0 +1,163 @@
+package ber
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestDecoderState(t *testing.T) {
+	// SEQUENCE { INTEGER 1, [1] (indefinite) { OCTET STRING "a" }, BOOLEAN TRUE }
+	data := []byte{
+		0x30, 0x0d,
+		0x02, 0x01, 0x01,
+		0xa1, 0x80, 0x04, 0x01, 'a', 0x00, 0x00,
+		0x01, 0x01, 0xff,
+	}
+
+	s := NewDecoderState(data)
+	for i, tc := range []struct {
+		tag     Tag
+		offset  int
+		depth   int
+		end     bool
+		content []byte
+		pending int
+	}{
+		{TagSequence, 0, 0, false, nil, 0},
+		{TagInteger, 2, 1, false, []byte{0x01}, 0},
+		{1, 5, 1, false, nil, 1},
+		{TagOctetString, 7, 2, false, []byte{'a'}, 1},
+		{0, 10, 1, true, nil, 0},
+		{TagBoolean, 12, 1, false, []byte{0xff}, 0},
+		{0, 15, 0, true, nil, 0},
+	} {
+		tlv, err := s.Next()
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if tlv.End != tc.end || tlv.Offset != tc.offset || tlv.Depth != tc.depth {
+			t.Errorf("%d: expected end=%t offset=%d depth=%d, got end=%t offset=%d depth=%d",
+				i, tc.end, tc.offset, tc.depth, tlv.End, tlv.Offset, tlv.Depth)
+		}
+		if !tc.end && (tlv.Tag != tc.tag || !bytes.Equal(tc.content, tlv.Content)) {
+			t.Errorf("%d: expected tag %d with content % X, got tag %d with content % X", i, tc.tag, tc.content, tlv.Tag, tlv.Content)
+		}
+		if s.PendingTerminators() != tc.pending {
+			t.Errorf("%d: expected %d pending terminators, got %d", i, tc.pending, s.PendingTerminators())
+		}
+	}
+	if _, err := s.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+	if s.Offset() != len(data) || s.Depth() != 0 {
+		t.Errorf("expected to be at the end, got offset %d and depth %d", s.Offset(), s.Depth())
+	}
+}
+
+func TestDecoderStateErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"truncated content", []byte{0x30, 0x03, 0x02, 0x05, 0x01}},
+		{"missing eoc", []byte{0x30, 0x80, 0x02, 0x01, 0x01}},
+		{"child exceeds parent", []byte{0x30, 0x02, 0x02, 0x02, 0x01, 0x01}},
+		{"eoc without indefinite parent", []byte{0x30, 0x02, 0x00, 0x00}},
+		{"indefinite primitive", []byte{0x04, 0x80, 0x00, 0x00}},
+	} {
+		s := NewDecoderState(tc.data)
+		var err error
+		for err == nil {
+			_, err = s.Next()
+		}
+		if err == io.EOF {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	// SEQUENCE { INTEGER, SEQUENCE { SEQUENCE { NULL } }, OCTET STRING } followed by trailing data
+	inner := NewSequence("")
+	inner.AppendChild(Encode(ClassUniversal, TypePrimitive, TagNULL, nil, ""))
+	middle := NewSequence("")
+	middle.AppendChild(inner)
+	root := NewSequence("")
+	root.AppendChild(Integer(1))
+	root.AppendChild(middle)
+	root.AppendChild(String("abc"))
+	data := append(root.Bytes(), 0x05, 0x00)
+
+	nodes, maxDepth, totalBytes, err := Profile(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if nodes != 6 || maxDepth != 4 || totalBytes != len(data)-2 {
+		t.Errorf("expected 6 nodes, depth 4 and %d bytes, got %d nodes, depth %d and %d bytes", len(data)-2, nodes, maxDepth, totalBytes)
+	}
+
+	if nodes, maxDepth, _, err := Profile([]byte{0x02, 0x01, 0x05}); err != nil || nodes != 1 || maxDepth != 1 {
+		t.Errorf("expected a single node of depth 1, got %d nodes, depth %d (%v)", nodes, maxDepth, err)
+	}
+	for _, invalid := range [][]byte{{}, {0x30, 0x03, 0x02, 0x01}} {
+		if _, _, _, err := Profile(invalid); err != io.ErrUnexpectedEOF {
+			t.Errorf("% X: expected io.ErrUnexpectedEOF, got %v", invalid, err)
+		}
+	}
+}
+
+func TestSplitSequence(t *testing.T) {
+	inner := NewSequence("")
+	inner.AppendChild(Integer(2))
+	inner.AppendChild(Null())
+	children := []*Packet{
+		Integer(1),
+		inner,
+		NewString(ClassUniversal, TypePrimitive, TagOctetString, "abc", ""),
+	}
+	sequence := NewSequence("")
+	for _, child := range children {
+		sequence.AppendChild(child)
+	}
+	data := append(sequence.Bytes(), 0x05, 0x00)
+
+	parts, err := SplitSequence(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parts) != len(children) {
+		t.Fatalf("expected %d children, got %d", len(children), len(parts))
+	}
+	for i, part := range parts {
+		if !bytes.Equal(children[i].Bytes(), part) {
+			t.Errorf("%d: expected % X, got % X", i, children[i].Bytes(), part)
+		}
+		p, err := DecodeExact(part)
+		if err != nil {
+			t.Errorf("%d: unexpected error decoding % X: %v", i, part, err)
+		} else if !p.Equal(children[i]) {
+			t.Errorf("%d: decoded as % X", i, p.Bytes())
+		}
+	}
+
+	// indefinite length with a constructed child
+	indefinite := []byte{0x30, 0x80, 0x30, 0x80, 0x05, 0x00, 0x00, 0x00, 0x02, 0x01, 0x01, 0x00, 0x00}
+	if parts, err := SplitSequence(indefinite); err != nil || len(parts) != 2 || !bytes.Equal(indefinite[2:8], parts[0]) {
+		t.Errorf("unexpected split of indefinite-length SEQUENCE: % X (%v)", parts, err)
+	}
+	if parts, err := SplitSequence([]byte{0x30, 0x00}); err != nil || len(parts) != 0 {
+		t.Errorf("expected no children of an empty SEQUENCE, got %d (%v)", len(parts), err)
+	}
+
+	for _, invalid := range [][]byte{
+		nil,
+		{0x31, 0x03, 0x02, 0x01, 0x01},
+		{0x30, 0x05, 0x02, 0x01, 0x01},
+		{0x30, 0x03, 0x02, 0x02, 0x01},
+	} {
+		if _, err := SplitSequence(invalid); err == nil {
+			t.Errorf("% X: expected error", invalid)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package ber
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Application tags of the Ember+ Glow DTD
+const (
+	TagGlowRoot                  Tag = 0
+	TagGlowParameter             Tag = 1
+	TagGlowCommand               Tag = 2
+	TagGlowNode                  Tag = 3
+	TagGlowElementCollection     Tag = 4
+	TagGlowStreamCollection      Tag = 6
+	TagGlowQualifiedParameter    Tag = 9
+	TagGlowQualifiedNode         Tag = 10
+	TagGlowRootElementCollection Tag = 11
+	TagGlowMatrix                Tag = 13
+	TagGlowQualifiedMatrix       Tag = 17
+	TagGlowFunction              Tag = 19
+	TagGlowQualifiedFunction     Tag = 20
+	TagGlowInvocationResult      Tag = 23
+	TagGlowTemplate              Tag = 24
+	TagGlowQualifiedTemplate     Tag = 25
+)
+
+// Command is the number of an Ember+ GlowCommand
+type Command int64
+
+const (
+	CommandSubscribe    Command = 30
+	CommandUnsubscribe  Command = 31
+	CommandGetDirectory Command = 32
+	CommandInvoke       Command = 33
+)
+
+var commandMap = map[Command]string{
+	CommandSubscribe:    "Subscribe",
+	CommandUnsubscribe:  "Unsubscribe",
+	CommandGetDirectory: "GetDirectory",
+	CommandInvoke:       "Invoke",
+}
+
+func (c Command) String() string {
+	if s, ok := commandMap[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("Command(%d)", int64(c))
+}
+
+// DecodeGlowCommand extracts the command number from a decoded Ember+
+// GlowCommand, i.e. [APPLICATION 2] SEQUENCE { number [0] INTEGER, ... }.
+func DecodeGlowCommand(p *Packet) (Command, error) {
+	if p == nil {
+		return 0, errors.New("nil packet")
+	}
+	if p.ClassType != ClassApplication || p.TagType != TypeConstructed || p.Tag != TagGlowCommand {
+		return 0, fmt.Errorf("not a GlowCommand: %s", DescribePacket(p))
+	}
+
+	for _, child := range p.Children {
+		if child.ClassType != ClassContext || child.Tag != 0 {
+			continue
+		}
+		if len(child.Children) != 1 {
+			return 0, errors.New("GlowCommand number must contain exactly one value")
+		}
+		v, ok := child.Children[0].Value.(int64)
+		if !ok {
+			return 0, fmt.Errorf("GlowCommand number has invalid type %T", child.Children[0].Value)
+		}
+		cmd := Command(v)
+		if _, ok := commandMap[cmd]; !ok {
+			return cmd, fmt.Errorf("unknown GlowCommand number %d", v)
+		}
+		return cmd, nil
+	}
+	return 0, errors.New("GlowCommand without number")
+}
+
+// EmberPath extracts the numeric path of a decoded Ember+ qualified element,
+// e.g. QualifiedNode ::= [APPLICATION 10] SEQUENCE { path [0] RELATIVE-OID, ... }.
+func EmberPath(p *Packet) ([]int, error) {
+	if p == nil {
+		return nil, errors.New("nil packet")
+	}
+	if !isQualifiedElement(p) {
+		return nil, fmt.Errorf("not a qualified element: %s", DescribePacket(p))
+	}
+
+	for _, child := range p.Children {
+		if child.ClassType != ClassContext || child.Tag != 0 {
+			continue
+		}
+		if len(child.Children) != 1 {
+			return nil, errors.New("qualified element path must contain exactly one value")
+		}
+		oid := child.Children[0]
+		if oid.ClassType != ClassUniversal || oid.TagType != TypePrimitive || oid.Tag != TagRelativeOID {
+			return nil, errors.New("qualified element path must be a RELATIVE-OID")
+		}
+		return parseRelativeObjectIdentifier(oid.Data.Bytes())
+	}
+	return nil, errors.New("qualified element without path")
+}
+
+func isQualifiedElement(p *Packet) bool {
+	if p.ClassType != ClassApplication || p.TagType != TypeConstructed {
+		return false
+	}
+	switch p.Tag {
+	case TagGlowQualifiedParameter, TagGlowQualifiedNode, TagGlowQualifiedMatrix, TagGlowQualifiedFunction:
+		return true
+	}
+	return false
+}
+
+// EmberDelta compares two snapshots of an Ember+ tree and returns the
+// subtrees of next that changed, for sending subscription updates. Each
+// change is reported as the innermost qualified element of next containing it,
+// or next itself if there is none. Children are compared by position, so an
+// element added, removed or moved reports its parent as changed.
+func EmberDelta(prev, next *Packet) ([]*Packet, error) {
+	if prev == nil || next == nil {
+		return nil, errors.New("nil packet")
+	}
+	var delta []*Packet
+	emberDelta(prev, next, next, &delta)
+	return delta, nil
+}
+
+func emberDelta(prev, next, enclosing *Packet, delta *[]*Packet) {
+	if bytes.Equal(prev.Bytes(), next.Bytes()) {
+		return
+	}
+	if isQualifiedElement(next) {
+		enclosing = next
+	}
+	if prev.Identifier != next.Identifier || next.TagType == TypePrimitive || len(prev.Children) != len(next.Children) {
+		if n := len(*delta); n == 0 || (*delta)[n-1] != enclosing {
+			*delta = append(*delta, enclosing)
+		}
+		return
+	}
+	for i := range next.Children {
+		emberDelta(prev.Children[i], next.Children[i], enclosing, delta)
+	}
+}
+
+// emberElementType describes the fields of a Glow element type.
+type emberElementType struct {
+	name      string
+	qualified bool
+	// lastField is the highest context tag of its fields
+	lastField Tag
+	// children is the context tag of its ElementCollection, 0 if it has none
+	children Tag
+}
+
+var emberElementTypes = map[Tag]emberElementType{
+	TagGlowParameter:          {"Parameter", false, 2, 2},
+	TagGlowCommand:            {"Command", false, 2, 0},
+	TagGlowNode:               {"Node", false, 2, 2},
+	TagGlowMatrix:             {"Matrix", false, 5, 2},
+	TagGlowFunction:           {"Function", false, 2, 2},
+	TagGlowTemplate:           {"Template", false, 2, 0},
+	TagGlowQualifiedParameter: {"QualifiedParameter", true, 2, 2},
+	TagGlowQualifiedNode:      {"QualifiedNode", true, 2, 2},
+	TagGlowQualifiedMatrix:    {"QualifiedMatrix", true, 5, 2},
+	TagGlowQualifiedFunction:  {"QualifiedFunction", true, 2, 2},
+	TagGlowQualifiedTemplate:  {"QualifiedTemplate", true, 2, 0},
+}
+
+// ValidateEmberTree checks a decoded Ember+ tree against the structure of the
+// Glow DTD. The root must be a Root holding a RootElementCollection, a
+// StreamCollection or an InvocationResult. Collections must hold elements
+// wrapped in [0], with qualified elements only in the RootElementCollection.
+// The fields of an element must appear in ascending order, starting with its
+// number or path. Parameter values must be of one of the Glow Value types.
+func ValidateEmberTree(p *Packet) error {
+	if p == nil {
+		return errors.New("nil packet")
+	}
+	if !isGlowConstructed(p, TagGlowRoot) {
+		return fmt.Errorf("not an Ember+ Root: %s", DescribePacket(p))
+	}
+	if len(p.Children) != 1 {
+		return fmt.Errorf("Ember+ Root must contain exactly one value, got %d", len(p.Children))
+	}
+	child := p.Children[0]
+	switch {
+	case isGlowConstructed(child, TagGlowRootElementCollection):
+		return validateEmberCollection(child, true)
+	case isGlowConstructed(child, TagGlowStreamCollection), isGlowConstructed(child, TagGlowInvocationResult):
+		return nil
+	}
+	return fmt.Errorf("invalid Ember+ Root content: %s", DescribePacket(child))
+}
+
+func isGlowConstructed(p *Packet, tag Tag) bool {
+	return p.ClassType == ClassApplication && p.TagType == TypeConstructed && p.Tag == tag
+}
+
+// emberField returns the value of the explicitly tagged field p, i.e. a
+// constructed [n] holding exactly one value.
+func emberField(p *Packet) (*Packet, error) {
+	if p.ClassType != ClassContext || p.TagType != TypeConstructed || len(p.Children) != 1 {
+		return nil, fmt.Errorf("field must be a context-specific tag holding one value, got %s", DescribePacket(p))
+	}
+	return p.Children[0], nil
+}
+
+// validateEmberCollection validates the elements of a RootElementCollection,
+// or of an ElementCollection if root is false.
+func validateEmberCollection(p *Packet, root bool) error {
+	for i, wrapper := range p.Children {
+		element, err := emberField(wrapper)
+		if err == nil && wrapper.Tag != 0 {
+			err = fmt.Errorf("element must be tagged [0], got [%d]", wrapper.Tag)
+		}
+		if err == nil {
+			err = validateEmberElement(element, root)
+		}
+		if err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateEmberElement(p *Packet, root bool) error {
+	elementType, ok := emberElementTypes[p.Tag]
+	if !ok || p.ClassType != ClassApplication || p.TagType != TypeConstructed {
+		return fmt.Errorf("not an Ember+ element: %s", DescribePacket(p))
+	}
+	if elementType.qualified && !root {
+		return fmt.Errorf("%s only allowed in the RootElementCollection", elementType.name)
+	}
+	if len(p.Children) == 0 || p.Children[0].Tag != 0 {
+		return fmt.Errorf("%s must start with its number or path [0]", elementType.name)
+	}
+
+	for i, field := range p.Children {
+		value, err := emberField(field)
+		if err != nil {
+			return fmt.Errorf("%s: %w", elementType.name, err)
+		}
+		if i > 0 && field.Tag <= p.Children[i-1].Tag {
+			return fmt.Errorf("%s field [%d] must not follow [%d]", elementType.name, field.Tag, p.Children[i-1].Tag)
+		}
+		if field.Tag > elementType.lastField {
+			return fmt.Errorf("%s has no field [%d]", elementType.name, field.Tag)
+		}
+
+		switch {
+		case field.Tag == 0 && elementType.qualified:
+			if !isUniversalPrimitive(value, TagRelativeOID) {
+				return fmt.Errorf("%s path must be a RELATIVE-OID", elementType.name)
+			}
+		case field.Tag == 0:
+			if !isUniversalPrimitive(value, TagInteger) {
+				return fmt.Errorf("%s number must be an INTEGER", elementType.name)
+			}
+		case field.Tag == 1 && (p.Tag == TagGlowParameter || p.Tag == TagGlowQualifiedParameter):
+			if err := validateEmberParameterContents(value); err != nil {
+				return fmt.Errorf("%s: %w", elementType.name, err)
+			}
+		case field.Tag == elementType.children:
+			if !isGlowConstructed(value, TagGlowElementCollection) {
+				return fmt.Errorf("%s children must be an ElementCollection", elementType.name)
+			}
+			if err := validateEmberCollection(value, false); err != nil {
+				return fmt.Errorf("%s children: %w", elementType.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEmberParameterContents checks the types of the value, minimum,
+// maximum and default fields of ParameterContents.
+func validateEmberParameterContents(p *Packet) error {
+	if p.ClassType != ClassUniversal || p.TagType != TypeConstructed || p.Tag != TagSet {
+		return errors.New("ParameterContents must be a SET")
+	}
+	for _, field := range p.Children {
+		value, err := emberField(field)
+		if err != nil {
+			return err
+		}
+		switch field.Tag {
+		case 2, 12: // value, default
+			if !isEmberValue(value) {
+				return fmt.Errorf("invalid type of ParameterContents field [%d]: %s", field.Tag, DescribePacket(value))
+			}
+		case 3, 4: // minimum, maximum
+			if !isUniversalPrimitive(value, TagInteger) && !isUniversalPrimitive(value, TagRealFloat) && !isUniversalPrimitive(value, TagNULL) {
+				return fmt.Errorf("invalid type of ParameterContents field [%d]: %s", field.Tag, DescribePacket(value))
+			}
+		}
+	}
+	return nil
+}
+
+// isEmberValue reports whether p is one of the types of the Glow Value CHOICE.
+func isEmberValue(p *Packet) bool {
+	for _, tag := range []Tag{TagInteger, TagRealFloat, TagUTF8String, TagBoolean, TagOctetString, TagNULL} {
+		if isUniversalPrimitive(p, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUniversalPrimitive(p *Packet, tag Tag) bool {
+	return p.ClassType == ClassUniversal && p.TagType == TypePrimitive && p.Tag == tag
+}
@@ -0,0 +1,176 @@
+package ber
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeGlowCommand(t *testing.T) {
+	// [APPLICATION 2] { [0] { INTEGER 32 } }
+	data := []byte{0x62, 0x05, 0xa0, 0x03, 0x02, 0x01, 0x20}
+
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error decoding packet: %v", err)
+	}
+
+	cmd, err := DecodeGlowCommand(p)
+	if err != nil {
+		t.Fatalf("unexpected error decoding command: %v", err)
+	}
+	if cmd != CommandGetDirectory {
+		t.Errorf("expected %s, got %s", CommandGetDirectory, cmd)
+	}
+
+	if _, err := DecodeGlowCommand(NewSequence("")); err == nil {
+		t.Error("expected error decoding a plain sequence as command")
+	}
+}
+
+func TestEmberPath(t *testing.T) {
+	path := Encode(ClassContext, TypeConstructed, 0, nil, "path")
+	path.AppendChild(NewRelativeOID(ClassUniversal, TypePrimitive, TagRelativeOID, "1.2.300", "path"))
+	contents := Encode(ClassContext, TypeConstructed, 1, nil, "contents")
+	contents.AppendChild(NewSequence("NodeContents"))
+
+	node := Encode(ClassApplication, TypeConstructed, TagGlowQualifiedNode, nil, "QualifiedNode")
+	node.AppendChild(path)
+	node.AppendChild(contents)
+
+	decoded, err := DecodePacketErr(node.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error decoding packet: %v", err)
+	}
+	got, err := EmberPath(decoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []int{1, 2, 300}; !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected path %v, got %v", expected, got)
+	}
+
+	if _, err := EmberPath(NewSequence("")); err == nil {
+		t.Error("expected error for a plain sequence")
+	}
+}
+
+func TestEmberDelta(t *testing.T) {
+	parameter := func(path string, value int64) *Packet {
+		p := Encode(ClassApplication, TypeConstructed, TagGlowQualifiedParameter, nil, "QualifiedParameter")
+		pathTag := Encode(ClassContext, TypeConstructed, 0, nil, "path")
+		pathTag.AppendChild(NewRelativeOID(ClassUniversal, TypePrimitive, TagRelativeOID, path, "path"))
+		p.AppendChild(pathTag)
+		contents := Encode(ClassContext, TypeConstructed, 1, nil, "contents")
+		set := Encode(ClassUniversal, TypeConstructed, TagSet, nil, "ParameterContents")
+		valueTag := Encode(ClassContext, TypeConstructed, 2, nil, "value")
+		valueTag.AppendChild(Integer(value))
+		set.AppendChild(valueTag)
+		contents.AppendChild(set)
+		p.AppendChild(contents)
+		return p
+	}
+	root := func(gain, volume int64) *Packet {
+		collection := Encode(ClassApplication, TypeConstructed, 11, nil, "RootElementCollection")
+		for _, element := range []*Packet{parameter("1.1", gain), parameter("1.2", volume)} {
+			wrapper := Encode(ClassContext, TypeConstructed, 0, nil, "")
+			wrapper.AppendChild(element)
+			collection.AppendChild(wrapper)
+		}
+		r := Encode(ClassApplication, TypeConstructed, 0, nil, "Root")
+		r.AppendChild(collection)
+		return r
+	}
+
+	prev, next := root(-6, 80), root(-6, 75)
+	delta, err := EmberDelta(prev, next)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(delta) != 1 {
+		t.Fatalf("expected a single changed element, got %d", len(delta))
+	}
+	if path, err := EmberPath(delta[0]); err != nil || !reflect.DeepEqual([]int{1, 2}, path) {
+		t.Errorf("expected element 1.2 to change, got %v (%v)", path, err)
+	}
+
+	if delta, err := EmberDelta(prev, root(-6, 80)); err != nil || len(delta) != 0 {
+		t.Errorf("expected no delta for equal trees, got %d (%v)", len(delta), err)
+	}
+	if delta, _ := EmberDelta(prev, NewSequence("")); len(delta) != 1 || delta[0].Tag != TagSequence {
+		t.Error("expected the new root as delta for an unrelated tree")
+	}
+	if _, err := EmberDelta(nil, next); err == nil {
+		t.Error("expected error for a nil packet")
+	}
+}
+
+func TestValidateEmberTree(t *testing.T) {
+	field := func(tag Tag, value *Packet) *Packet {
+		f := Encode(ClassContext, TypeConstructed, tag, nil, "")
+		f.AppendChild(value)
+		return f
+	}
+	element := func(tag Tag, fields ...*Packet) *Packet {
+		e := Encode(ClassApplication, TypeConstructed, tag, nil, "")
+		for _, f := range fields {
+			e.AppendChild(f)
+		}
+		return e
+	}
+	collection := func(tag Tag, elements ...*Packet) *Packet {
+		c := Encode(ClassApplication, TypeConstructed, tag, nil, "")
+		for _, e := range elements {
+			c.AppendChild(field(0, e))
+		}
+		return c
+	}
+	contents := func(value *Packet) *Packet {
+		set := Encode(ClassUniversal, TypeConstructed, TagSet, nil, "ParameterContents")
+		set.AppendChild(field(0, NewString(ClassUniversal, TypePrimitive, TagUTF8String, "gain", "")))
+		set.AppendChild(field(2, value))
+		return set
+	}
+	root := func(elements ...*Packet) *Packet {
+		r := Encode(ClassApplication, TypeConstructed, TagGlowRoot, nil, "Root")
+		r.AppendChild(collection(TagGlowRootElementCollection, elements...))
+		return r
+	}
+	nodeContents := Encode(ClassUniversal, TypeConstructed, TagSet, nil, "NodeContents")
+	gain := element(TagGlowParameter, field(0, Integer(1)), field(1, contents(Integer(-6))))
+	path := NewRelativeOID(ClassUniversal, TypePrimitive, TagRelativeOID, "1.2", "")
+
+	valid := root(
+		element(TagGlowNode,
+			field(0, Integer(1)),
+			field(1, nodeContents),
+			field(2, collection(TagGlowElementCollection, gain))),
+		element(TagGlowQualifiedParameter, field(0, path), field(1, contents(String("on")))),
+	)
+	decoded, err := DecodePacketErr(valid.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error decoding: %v", err)
+	}
+	if err := ValidateEmberTree(decoded); err != nil {
+		t.Errorf("unexpected error for a valid tree: %v", err)
+	}
+
+	for name, tc := range map[string]*Packet{
+		"child order": root(element(TagGlowNode,
+			field(0, Integer(1)),
+			field(2, collection(TagGlowElementCollection, gain)),
+			field(1, nodeContents))),
+		"number not first": root(element(TagGlowNode, field(1, nodeContents))),
+		"duplicate field":  root(element(TagGlowParameter, field(0, Integer(1)), field(1, contents(Integer(1))), field(1, contents(Integer(2))))),
+		"value type":       root(element(TagGlowParameter, field(0, Integer(1)), field(1, contents(NewSequence(""))))),
+		"path type":        root(element(TagGlowQualifiedNode, field(0, Integer(1)))),
+		"nested qualified": root(element(TagGlowNode, field(0, Integer(1)), field(2, collection(TagGlowElementCollection,
+			element(TagGlowQualifiedParameter, field(0, path)))))),
+		"root":          collection(TagGlowRootElementCollection, gain),
+		"root content":  element(TagGlowRoot, gain),
+		"unknown field": root(element(TagGlowNode, field(0, Integer(1)), field(7, Integer(1)))),
+	} {
+		if err := ValidateEmberTree(tc); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
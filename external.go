@@ -0,0 +1,112 @@
+package ber
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExternalEncoding identifies the alternative of the encoding CHOICE of an EXTERNAL.
+type ExternalEncoding int
+
+const (
+	ExternalSingleASN1Type ExternalEncoding = 0
+	ExternalOctetAligned   ExternalEncoding = 1
+	ExternalArbitrary      ExternalEncoding = 2
+)
+
+// External holds a decoded EXTERNAL value (x.690, 8.18).
+type External struct {
+	// DirectReference is the dotted OBJECT IDENTIFIER, empty if absent
+	DirectReference string
+	// IndirectReference is nil if absent
+	IndirectReference   *int64
+	DataValueDescriptor string
+
+	Encoding ExternalEncoding
+	// SingleASN1Type is set for ExternalSingleASN1Type
+	SingleASN1Type *Packet
+	// OctetAligned is set for ExternalOctetAligned
+	OctetAligned []byte
+	// Arbitrary holds the BIT STRING content octets, including the leading
+	// unused bits octet, for ExternalArbitrary
+	Arbitrary []byte
+}
+
+// ParseExternal decodes an EXTERNAL packet:
+//
+//	EXTERNAL ::= [UNIVERSAL 8] IMPLICIT SEQUENCE {
+//	    direct-reference      OBJECT IDENTIFIER OPTIONAL,
+//	    indirect-reference    INTEGER OPTIONAL,
+//	    data-value-descriptor ObjectDescriptor OPTIONAL,
+//	    encoding CHOICE {
+//	        single-ASN1-type [0] ABSTRACT-SYNTAX.&Type,
+//	        octet-aligned    [1] IMPLICIT OCTET STRING,
+//	        arbitrary        [2] IMPLICIT BIT STRING } }
+func ParseExternal(p *Packet) (*External, error) {
+	if p == nil {
+		return nil, errors.New("nil packet")
+	}
+	if p.ClassType != ClassUniversal || p.TagType != TypeConstructed || p.Tag != TagExternal {
+		return nil, errors.New("EXTERNAL must be a constructed universal tag 8")
+	}
+	if len(p.Children) == 0 {
+		return nil, errors.New("EXTERNAL without encoding")
+	}
+
+	ext := &External{}
+	children := p.Children
+	if c := children[0]; c.ClassType == ClassUniversal && c.Tag == TagObjectIdentifier {
+		v, ok := c.Value.(string)
+		if !ok {
+			return nil, errors.New("invalid EXTERNAL direct-reference")
+		}
+		ext.DirectReference = v
+		children = children[1:]
+	}
+	if len(children) > 0 && children[0].ClassType == ClassUniversal && children[0].Tag == TagInteger {
+		v, ok := children[0].Value.(int64)
+		if !ok {
+			return nil, errors.New("invalid EXTERNAL indirect-reference")
+		}
+		ext.IndirectReference = &v
+		children = children[1:]
+	}
+	if len(children) > 0 && children[0].ClassType == ClassUniversal && children[0].Tag == TagObjectDescriptor {
+		ext.DataValueDescriptor = DecodeString(children[0].Data.Bytes())
+		children = children[1:]
+	}
+
+	if len(children) != 1 {
+		return nil, fmt.Errorf("EXTERNAL must end with exactly one encoding, got %d elements", len(children))
+	}
+	encoding := children[0]
+	if encoding.ClassType != ClassContext {
+		return nil, errors.New("EXTERNAL encoding must be context-specific")
+	}
+
+	switch ExternalEncoding(encoding.Tag) {
+	case ExternalSingleASN1Type:
+		if encoding.TagType != TypeConstructed || len(encoding.Children) != 1 {
+			return nil, errors.New("EXTERNAL single-ASN1-type must contain exactly one value")
+		}
+		ext.SingleASN1Type = encoding.Children[0]
+	case ExternalOctetAligned:
+		if encoding.TagType != TypePrimitive {
+			return nil, errors.New("EXTERNAL octet-aligned must be primitive")
+		}
+		ext.OctetAligned = encoding.Data.Bytes()
+	case ExternalArbitrary:
+		if encoding.TagType != TypePrimitive {
+			return nil, errors.New("EXTERNAL arbitrary must be primitive")
+		}
+		content := encoding.Data.Bytes()
+		if len(content) == 0 || content[0] > 7 {
+			return nil, errors.New("invalid EXTERNAL arbitrary BIT STRING")
+		}
+		ext.Arbitrary = content
+	default:
+		return nil, fmt.Errorf("invalid EXTERNAL encoding [%d]", encoding.Tag)
+	}
+	ext.Encoding = ExternalEncoding(encoding.Tag)
+	return ext, nil
+}
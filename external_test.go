@@ -0,0 +1,53 @@
+package ber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newTestExternal(encoding *Packet) *Packet {
+	p := Encode(ClassUniversal, TypeConstructed, TagExternal, nil, "EXTERNAL")
+	p.AppendChild(NewOID(ClassUniversal, TypePrimitive, TagObjectIdentifier, "2.1.1", "direct-reference"))
+	p.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, 3, "indirect-reference"))
+	p.AppendChild(encoding)
+	return DecodePacket(p.Bytes())
+}
+
+func TestParseExternal(t *testing.T) {
+	single := Encode(ClassContext, TypeConstructed, 0, nil, "single-ASN1-type")
+	single.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, 42, ""))
+
+	ext, err := ParseExternal(newTestExternal(single))
+	if err != nil {
+		t.Fatalf("single-ASN1-type: unexpected error: %v", err)
+	}
+	if ext.Encoding != ExternalSingleASN1Type || ext.SingleASN1Type == nil || ext.SingleASN1Type.Value != int64(42) {
+		t.Errorf("single-ASN1-type: unexpected result %+v", ext)
+	}
+	if ext.DirectReference != "2.1.1" || ext.IndirectReference == nil || *ext.IndirectReference != 3 {
+		t.Errorf("single-ASN1-type: unexpected references %+v", ext)
+	}
+
+	octets := NewString(ClassContext, TypePrimitive, 1, "\x01\x02\x03", "octet-aligned")
+	ext, err = ParseExternal(newTestExternal(octets))
+	if err != nil {
+		t.Fatalf("octet-aligned: unexpected error: %v", err)
+	}
+	if ext.Encoding != ExternalOctetAligned || !bytes.Equal([]byte{0x01, 0x02, 0x03}, ext.OctetAligned) {
+		t.Errorf("octet-aligned: unexpected result %+v", ext)
+	}
+
+	bits := NewString(ClassContext, TypePrimitive, 2, "\x04\xF0", "arbitrary")
+	ext, err = ParseExternal(newTestExternal(bits))
+	if err != nil {
+		t.Fatalf("arbitrary: unexpected error: %v", err)
+	}
+	if ext.Encoding != ExternalArbitrary || !bytes.Equal([]byte{0x04, 0xF0}, ext.Arbitrary) {
+		t.Errorf("arbitrary: unexpected result %+v", ext)
+	}
+
+	invalid := NewString(ClassContext, TypePrimitive, 3, "", "invalid")
+	if _, err := ParseExternal(newTestExternal(invalid)); err == nil {
+		t.Error("expected error for invalid encoding choice")
+	}
+}
@@ -8,6 +8,7 @@
 package ber
 
 import (
+	"bytes"
 	"os"
 	"testing"
 	"time"
@@ -27,8 +28,9 @@ func FuzzDecodePacket(f *testing.F) {
 	}
 
 	// Seed the fuzz corpus with data known to cause panics in the past
-	f.Add([]byte{0x09, 0x02, 0x85, 0x30})
-	f.Add([]byte{0x09, 0x01, 0xcf})
+	for _, data := range loadCorpus(f) {
+		f.Add(data)
+	}
 
 	f.Fuzz(func(t *testing.T, data []byte) {
 		stime := time.Now()
@@ -43,3 +45,55 @@ func FuzzDecodePacket(f *testing.F) {
 		}
 	})
 }
+
+func FuzzReadPacket(f *testing.F) {
+	// Seed the fuzz corpus with the vectors of TestEOF
+	for _, data := range [][]byte{
+		{0x04, 0x0a, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02},
+		{0x30, 0x80, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x00, 0x00},
+		{0x1f, 0xff},
+		{0x04},
+		{0x04, 0x82, 0x02},
+		{0x04, 0x0a},
+		{0x04, 0x0a, 0, 1, 2},
+		{0x30, 0x06},
+		{0x30, 0x06, 0x02, 0x01, 0x01},
+		{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01},
+		{0x30, 0x80, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02},
+		{0x30, 0x80, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x00},
+	} {
+		f.Add(data)
+	}
+	// and with REALs, whose content is parsed further
+	for _, data := range [][]byte{
+		{0x09, 0x03, 0x80, 0xFE, 0x01},
+		{0x09, 0x05, 0x83, 0x01, 0xFF, 0x03, 0x01},
+		{0x09, 0x04, 0x03, '1', 'E', '2'},
+		{0x09, 0x01, 0x40},
+	} {
+		f.Add(data)
+	}
+	for _, data := range loadCorpus(f) {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := ReadPacket(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		// Lengths are re-encoded in their minimal definite form, so the
+		// encoding only matches the input for DER, but must decode to the
+		// same packet
+		encoded := p.Bytes()
+		again, err := ReadPacket(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatalf("re-encoding % X of % X does not decode: %v", encoded, data, err)
+		}
+		if !again.Equal(p) {
+			t.Fatalf("re-encoding % X of % X decodes to a different packet", encoded, data)
+		}
+	})
+}
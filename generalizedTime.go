@@ -103,3 +103,31 @@ func ParseGeneralizedTime(v []byte) (time.Time, error) {
 	}
 	return t.Add(fract), nil
 }
+
+// ParseTimeInterval decodes a SEQUENCE of two GeneralizedTime values, as used
+// by some schemas to encode a time interval, and returns its start and end.
+func ParseTimeInterval(data []byte) (start, end time.Time, err error) {
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		return zeroTime, zeroTime, err
+	}
+	if p.ClassType != ClassUniversal || p.TagType != TypeConstructed || p.Tag != TagSequence {
+		return zeroTime, zeroTime, errors.New("time interval must be a SEQUENCE")
+	}
+	if len(p.Children) != 2 {
+		return zeroTime, zeroTime, fmt.Errorf("time interval must have 2 elements, got %d", len(p.Children))
+	}
+
+	times := make([]time.Time, 2)
+	for i, child := range p.Children {
+		t, ok := child.Value.(time.Time)
+		if child.ClassType != ClassUniversal || child.Tag != TagGeneralizedTime || !ok {
+			return zeroTime, zeroTime, fmt.Errorf("time interval element %d is not a GeneralizedTime", i)
+		}
+		times[i] = t
+	}
+	if times[1].Before(times[0]) {
+		return zeroTime, zeroTime, errors.New("time interval ends before it starts")
+	}
+	return times[0], times[1], nil
+}
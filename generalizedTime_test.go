@@ -83,3 +83,75 @@ func TestParseGeneralizedTime(t *testing.T) {
 		}
 	}
 }
+
+func TestParseTimeInterval(t *testing.T) {
+	wantStart := time.Date(2023, time.January, 1, 12, 0, 0, 0, time.UTC)
+	wantEnd := time.Date(2023, time.January, 2, 12, 30, 0, 0, time.UTC)
+
+	sequence := NewSequence("interval")
+	sequence.AppendChild(NewGeneralizedTime(ClassUniversal, TypePrimitive, TagGeneralizedTime, wantStart, "start"))
+	sequence.AppendChild(NewGeneralizedTime(ClassUniversal, TypePrimitive, TagGeneralizedTime, wantEnd, "end"))
+
+	start, end, err := ParseTimeInterval(sequence.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !start.Equal(wantStart) || !end.Equal(wantEnd) {
+		t.Errorf("expected %s - %s, got %s - %s", wantStart, wantEnd, start, end)
+	}
+
+	reversed := NewSequence("interval")
+	reversed.AppendChild(NewGeneralizedTime(ClassUniversal, TypePrimitive, TagGeneralizedTime, wantEnd, "start"))
+	reversed.AppendChild(NewGeneralizedTime(ClassUniversal, TypePrimitive, TagGeneralizedTime, wantStart, "end"))
+	if _, _, err := ParseTimeInterval(reversed.Bytes()); err == nil {
+		t.Error("expected error for an interval ending before it starts")
+	}
+}
+
+func TestGeneralizedTimeRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{"20231225133000.5Z", "20231225133000.5Z"},
+		{"20231225133000,5Z", "20231225133000.5Z"},
+		{"20231225133000Z", "20231225133000Z"},
+		{"20231225143000.25+0100", "20231225133000.25Z"},
+	} {
+		p, err := DecodePacketErr(NewString(ClassUniversal, TypePrimitive, TagGeneralizedTime, tc.in, "").Bytes())
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.in, err)
+			continue
+		}
+		v, ok := p.Value.(time.Time)
+		if !ok {
+			t.Errorf("%s: expected time.Time value, got %T", tc.in, p.Value)
+			continue
+		}
+		encoded := NewGeneralizedTime(ClassUniversal, TypePrimitive, TagGeneralizedTime, v, "")
+		if got := string(encoded.Data.Bytes()); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.in, tc.want, got)
+		}
+		decoded, err := ParseGeneralizedTime(encoded.Data.Bytes())
+		if err != nil || !decoded.Equal(v) {
+			t.Errorf("%s: round trip returned %s (%v)", tc.in, decoded, err)
+		}
+	}
+}
+
+func TestNewGeneralizedTimeMinimal(t *testing.T) {
+	for _, tc := range []struct {
+		in   time.Time
+		want string
+	}{
+		{time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "20230101000000Z"},
+		{time.Date(2023, 1, 1, 0, 0, 0, 500*int(time.Millisecond), time.UTC), "20230101000000.5Z"},
+		{time.Date(2023, 1, 1, 0, 0, 0, 120*int(time.Millisecond), time.UTC), "20230101000000.12Z"},
+		{time.Date(2023, 1, 1, 0, 0, 0, 1, time.UTC), "20230101000000.000000001Z"},
+	} {
+		p := NewGeneralizedTime(ClassUniversal, TypePrimitive, TagGeneralizedTime, tc.in, "")
+		if got := string(p.Data.Bytes()); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.in, tc.want, got)
+		}
+	}
+}
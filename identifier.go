@@ -1,11 +1,16 @@
 package ber
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 )
 
+// maxTag is the largest tag number accepted by readIdentifier
+const maxTag = Tag(math.MaxInt64)
+
 func readIdentifier(reader io.Reader) (Identifier, int, error) {
 	identifier := Identifier{}
 	read := 0
@@ -42,6 +47,13 @@ func readIdentifier(reader io.Reader) (Identifier, int, error) {
 		tagBytes++
 		read++
 
+		// Overflow of int64, checked before shifting so that continuation bytes that
+		// never terminate are rejected as soon as the value can no longer fit
+		// TODO: support big int tags?
+		if identifier.Tag > maxTag>>7 {
+			return Identifier{}, read, errors.New("high-tag-number tag overflow")
+		}
+
 		// Lowest 7 bits get appended to the tag value (x.690, 8.1.2.4.2.b)
 		identifier.Tag <<= 7
 		identifier.Tag |= Tag(b) & HighTagValueBitmask
@@ -50,11 +62,6 @@ func readIdentifier(reader io.Reader) (Identifier, int, error) {
 		if tagBytes == 1 && identifier.Tag == 0 {
 			return Identifier{}, read, errors.New("invalid first high-tag-number tag byte")
 		}
-		// Overflow of int64
-		// TODO: support big int tags?
-		if tagBytes > 9 {
-			return Identifier{}, read, errors.New("high-tag-number tag overflow")
-		}
 
 		// Top bit of 0 means this is the last byte in the high-tag-number tag (x.690, 8.1.2.4.2.a)
 		if Tag(b)&HighTagContinueBitmask == 0 {
@@ -65,6 +72,16 @@ func readIdentifier(reader io.Reader) (Identifier, int, error) {
 	return identifier, read, nil
 }
 
+// Identify reads only the identifier of the top-level TLV in data and reports
+// it without decoding the contents.
+func Identify(data []byte) (class Class, typ Type, tag Tag, err error) {
+	identifier, _, err := readIdentifier(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return identifier.ClassType, identifier.TagType, identifier.Tag, nil
+}
+
 func encodeIdentifier(identifier Identifier) []byte {
 	b := []byte{0x0}
 	b[0] |= byte(identifier.ClassType)
@@ -84,6 +101,18 @@ func encodeIdentifier(identifier Identifier) []byte {
 	return b
 }
 
+// identifierLength returns the number of bytes encodeIdentifier produces for identifier.
+func identifierLength(identifier Identifier) int {
+	if identifier.Tag < HighTag {
+		return 1
+	}
+	n := 1
+	for tag := identifier.Tag; tag != 0; tag >>= 7 {
+		n++
+	}
+	return n
+}
+
 func encodeHighTag(tag Tag) []byte {
 	// set cap=4 to hopefully avoid additional allocations
 	b := make([]byte, 0, 4)
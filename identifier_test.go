@@ -2,6 +2,7 @@ package ber
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"math"
 	"testing"
@@ -124,6 +125,23 @@ func TestReadIdentifier(t *testing.T) {
 			ExpectedError:     "high-tag-number tag overflow",
 			ExpectedBytesRead: 11,
 		},
+		"high-tag-number tag continuation never terminates": {
+			Data: []byte{
+				byte(ClassUniversal) | byte(TypeConstructed) | byte(HighTag),
+				byte(HighTagContinueBitmask | 0x7f),
+				byte(HighTagContinueBitmask | 0x7f),
+				byte(HighTagContinueBitmask | 0x7f),
+				byte(HighTagContinueBitmask | 0x7f),
+				byte(HighTagContinueBitmask | 0x7f),
+				byte(HighTagContinueBitmask | 0x7f),
+				byte(HighTagContinueBitmask | 0x7f),
+				byte(HighTagContinueBitmask | 0x7f),
+				byte(HighTagContinueBitmask | 0x7f),
+				byte(HighTagContinueBitmask | 0x7f),
+			},
+			ExpectedError:     "high-tag-number tag overflow",
+			ExpectedBytesRead: 11,
+		},
 		"max high-tag-number tag": {
 			Data: []byte{
 				byte(ClassUniversal) | byte(TypeConstructed) | byte(HighTag),
@@ -361,3 +379,146 @@ func TestEncodeHighTag(t *testing.T) {
 		}
 	}
 }
+
+func TestIdentify(t *testing.T) {
+	application := Encode(ClassApplication, TypeConstructed, 1, nil, "")
+	application.AppendChild(NewInteger(ClassUniversal, TypePrimitive, TagInteger, 1, ""))
+
+	testCases := map[string]struct {
+		Data          []byte
+		ExpectedClass Class
+		ExpectedType  Type
+		ExpectedTag   Tag
+	}{
+		"sequence": {
+			Data:          NewSequence("").Bytes(),
+			ExpectedClass: ClassUniversal,
+			ExpectedType:  TypeConstructed,
+			ExpectedTag:   TagSequence,
+		},
+		"integer": {
+			Data:          NewInteger(ClassUniversal, TypePrimitive, TagInteger, 1024, "").Bytes(),
+			ExpectedClass: ClassUniversal,
+			ExpectedType:  TypePrimitive,
+			ExpectedTag:   TagInteger,
+		},
+		"application": {
+			Data:          application.Bytes(),
+			ExpectedClass: ClassApplication,
+			ExpectedType:  TypeConstructed,
+			ExpectedTag:   1,
+		},
+	}
+
+	for k, tc := range testCases {
+		class, typ, tag, err := Identify(tc.Data)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", k, err)
+			continue
+		}
+		if class != tc.ExpectedClass || typ != tc.ExpectedType || tag != tc.ExpectedTag {
+			t.Errorf("%s: expected (%d, %d, %d), got (%d, %d, %d)", k, tc.ExpectedClass, tc.ExpectedType, tc.ExpectedTag, class, typ, tag)
+		}
+	}
+
+	if _, _, _, err := Identify(nil); err != io.EOF {
+		t.Errorf("empty data: expected EOF, got %v", err)
+	}
+}
+
+func TestIdentifierLength(t *testing.T) {
+	for _, tag := range []Tag{0, 30, 31, 127, 128, 16383, 16384, Tag(math.MaxInt64)} {
+		identifier := Identifier{ClassType: ClassContext, TagType: TypeConstructed, Tag: tag}
+		if n, expected := identifierLength(identifier), len(encodeIdentifier(identifier)); n != expected {
+			t.Errorf("tag %d: expected length %d, got %d", tag, expected, n)
+		}
+	}
+}
+
+func TestPacketHighTagNumber(t *testing.T) {
+	for _, tc := range []struct {
+		tag      Tag
+		expected []byte
+	}{
+		{31, []byte{0x9f, 0x1f, 0x01, 0x2a}},
+		{127, []byte{0x9f, 0x7f, 0x01, 0x2a}},
+		{128, []byte{0x9f, 0x81, 0x00, 0x01, 0x2a}},
+		{200, []byte{0x9f, 0x81, 0x48, 0x01, 0x2a}},
+	} {
+		p := NewInteger(ClassContext, TypePrimitive, tc.tag, 42, "")
+		if !bytes.Equal(tc.expected, p.Bytes()) {
+			t.Errorf("tag %d: expected % X, got % X", tc.tag, tc.expected, p.Bytes())
+		}
+
+		decoded, err := DecodePacketErr(p.Bytes())
+		if err != nil {
+			t.Errorf("tag %d: unexpected error: %v", tc.tag, err)
+			continue
+		}
+		if decoded.ClassType != ClassContext || decoded.Tag != tc.tag || !bytes.Equal([]byte{0x2a}, decoded.Data.Bytes()) {
+			t.Errorf("tag %d: decoded as %s", tc.tag, DescribePacket(decoded))
+		}
+	}
+}
+
+func TestReadPacketHighTagNumber(t *testing.T) {
+	// [33] IMPLICIT OCTET STRING "x"
+	data := []byte{0x9f, 0x21, 0x01, 'x'}
+	p, err := ReadPacket(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.ClassType != ClassContext || p.TagType != TypePrimitive || p.Tag != 33 {
+		t.Errorf("expected context tag 33, got %s", DescribePacket(p))
+	}
+	if !bytes.Equal(data, p.Bytes()) {
+		t.Errorf("expected round trip to % X, got % X", data, p.Bytes())
+	}
+
+	for _, truncated := range [][]byte{{0x9f}, {0x9f, 0x81}, {0x9f, 0xff, 0xff}} {
+		if _, err := ReadPacket(bytes.NewReader(truncated)); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("% X: expected io.ErrUnexpectedEOF, got %v", truncated, err)
+		}
+	}
+}
+
+func TestPrivateClassRoundTrip(t *testing.T) {
+	// [PRIVATE 1000] { [PRIVATE 5] IMPLICIT INTEGER 7, OCTET STRING "ab", [PRIVATE 31] NULL }
+	data := []byte{
+		0xff, 0x87, 0x68, 0x0a,
+		0xc5, 0x01, 0x07,
+		0x04, 0x02, 'a', 'b',
+		0xdf, 0x1f, 0x00,
+	}
+
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Identifier != (Identifier{ClassType: ClassPrivate, TagType: TypeConstructed, Tag: 1000}) {
+		t.Errorf("expected constructed private tag 1000, got %s", DescribePacket(p))
+	}
+	if len(p.Children) != 3 {
+		t.Fatalf("expected 3 children, got %d", len(p.Children))
+	}
+	for i, expected := range []Identifier{
+		{ClassType: ClassPrivate, TagType: TypePrimitive, Tag: 5},
+		{ClassType: ClassUniversal, TagType: TypePrimitive, Tag: TagOctetString},
+		{ClassType: ClassPrivate, TagType: TypePrimitive, Tag: 31},
+	} {
+		if p.Children[i].Identifier != expected {
+			t.Errorf("child %d: expected %v, got %s", i, expected, DescribePacket(p.Children[i]))
+		}
+	}
+	if !bytes.Equal(data, p.Bytes()) {
+		t.Errorf("expected decoded packet to encode as % X, got % X", data, p.Bytes())
+	}
+
+	built := Encode(ClassPrivate, TypeConstructed, 1000, nil, "")
+	built.AppendChild(NewInteger(ClassPrivate, TypePrimitive, 5, 7, ""))
+	built.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "ab", ""))
+	built.AppendChild(Encode(ClassPrivate, TypePrimitive, 31, nil, ""))
+	if !bytes.Equal(data, built.Bytes()) {
+		t.Errorf("expected built packet to encode as % X, got % X", data, built.Bytes())
+	}
+}
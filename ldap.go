@@ -0,0 +1,16 @@
+package ber
+
+// NewLDAPResult returns the LDAPResult SEQUENCE (RFC 4511, 4.1.9) without referral:
+//
+//	LDAPResult ::= SEQUENCE {
+//	    resultCode        ENUMERATED,
+//	    matchedDN         LDAPDN,
+//	    diagnosticMessage LDAPString,
+//	    referral          [3] Referral OPTIONAL }
+func NewLDAPResult(resultCode int64, matchedDN, diagnosticMessage string) *Packet {
+	p := NewSequence("LDAPResult")
+	p.AppendChild(NewEnumerated(ClassUniversal, TypePrimitive, TagEnumerated, resultCode, "resultCode"))
+	p.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, matchedDN, "matchedDN"))
+	p.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, diagnosticMessage, "diagnosticMessage"))
+	return p
+}
@@ -0,0 +1,42 @@
+package ber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewLDAPResult(t *testing.T) {
+	for _, tc := range []struct {
+		resultCode        int64
+		matchedDN         string
+		diagnosticMessage string
+		encoded           []byte
+	}{
+		{0, "", "", []byte{0x30, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00}},
+		{32, "dc=example,dc=com", "no such entry", nil},
+	} {
+		p := NewLDAPResult(tc.resultCode, tc.matchedDN, tc.diagnosticMessage)
+		if tc.encoded != nil && !bytes.Equal(tc.encoded, p.Bytes()) {
+			t.Errorf("result %d: unexpected encoding % X", tc.resultCode, p.Bytes())
+		}
+
+		decoded, err := DecodePacketErr(p.Bytes())
+		if err != nil {
+			t.Errorf("result %d: unexpected error: %v", tc.resultCode, err)
+			continue
+		}
+		if len(decoded.Children) != 3 {
+			t.Errorf("result %d: expected 3 elements, got %d", tc.resultCode, len(decoded.Children))
+			continue
+		}
+		if decoded.Children[0].Tag != TagEnumerated || decoded.Children[0].Value != tc.resultCode {
+			t.Errorf("result %d: unexpected resultCode %v", tc.resultCode, decoded.Children[0].Value)
+		}
+		if decoded.Children[1].Value != tc.matchedDN {
+			t.Errorf("result %d: unexpected matchedDN %v", tc.resultCode, decoded.Children[1].Value)
+		}
+		if decoded.Children[2].Value != tc.diagnosticMessage {
+			t.Errorf("result %d: unexpected diagnosticMessage %v", tc.resultCode, decoded.Children[2].Value)
+		}
+	}
+}
@@ -79,3 +79,11 @@ func encodeLength(length int) []byte {
 	}
 	return lengthBytes
 }
+
+// lengthLength returns the number of bytes encodeLength produces for length.
+func lengthLength(length int) int {
+	if length > 127 {
+		return 1 + uint64Length(uint64(length))
+	}
+	return 1
+}
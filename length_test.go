@@ -189,3 +189,11 @@ func TestEncodeLength(t *testing.T) {
 		}
 	}
 }
+
+func TestLengthLength(t *testing.T) {
+	for _, length := range []int{0, 1, 127, 128, 255, 256, 65535, 65536, math.MaxInt32} {
+		if n, expected := lengthLength(length), len(encodeLength(length)); n != expected {
+			t.Errorf("length %d: expected %d bytes, got %d", length, expected, n)
+		}
+	}
+}
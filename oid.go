@@ -0,0 +1,185 @@
+package ber
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// OID is an OBJECT IDENTIFIER as a list of arcs.
+type OID []int
+
+// String returns the dotted representation of the OID, matching the Value of
+// decoded OBJECT IDENTIFIER packets.
+func (oid OID) String() string {
+	return OIDToString(oid)
+}
+
+// ChildByOID searches the immediate children of p, e.g. a SEQUENCE of
+// AttributeTypeAndValue, for a constructed child whose first element is an
+// OBJECT IDENTIFIER equal to oid.
+func (p *Packet) ChildByOID(oid OID) (*Packet, bool) {
+	s := oid.String()
+	for _, child := range p.Children {
+		if child == nil || len(child.Children) == 0 {
+			continue
+		}
+		first := child.Children[0]
+		if first.ClassType != ClassUniversal || first.Tag != TagObjectIdentifier {
+			continue
+		}
+		if v, ok := first.Value.(string); ok && v == s {
+			return child, true
+		}
+	}
+	return nil, false
+}
+
+// OIDEqual reports whether a and b consist of the same arcs.
+func OIDEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// OIDHasPrefix reports whether oid lies within the subtree prefix, e.g. to
+// detect the end of an SNMP walk. Arcs are compared as numbers, so 1.2.30 is
+// not within 1.2.3. An OID is within its own subtree.
+func OIDHasPrefix(oid, prefix []int) bool {
+	return len(oid) >= len(prefix) && OIDEqual(oid[:len(prefix)], prefix)
+}
+
+// EncodeOIDPacket returns the complete universal OBJECT IDENTIFIER TLV for oid,
+// for callers only interested in the encoded form.
+func EncodeOIDPacket(oid OID) ([]byte, error) {
+	if err := validateObjectIdentifier(oid); err != nil {
+		return nil, err
+	}
+	return NewOID(ClassUniversal, TypePrimitive, TagObjectIdentifier, oid.String(), "").Bytes(), nil
+}
+
+// parseObjectIdentifierBig is like parseObjectIdentifier, but supports arcs of
+// arbitrary size, e.g. UUIDs below 2.25 (ITU-T X.667).
+func parseObjectIdentifierBig(bytes []byte) ([]*big.Int, error) {
+	if len(bytes) == 0 {
+		return nil, errors.New("zero length OBJECT IDENTIFIER")
+	}
+
+	var oid []*big.Int
+	for offset := 0; offset < len(bytes); {
+		v, next, err := parseBase128BigInt(bytes, offset)
+		if err != nil {
+			return nil, err
+		}
+		if offset == 0 {
+			// The first subidentifier is 40*value1 + value2, see parseObjectIdentifier
+			if v.Cmp(big.NewInt(80)) < 0 {
+				oid = append(oid, big.NewInt(v.Int64()/40), big.NewInt(v.Int64()%40))
+			} else {
+				oid = append(oid, big.NewInt(2), v.Sub(v, big.NewInt(80)))
+			}
+		} else {
+			oid = append(oid, v)
+		}
+		offset = next
+	}
+	return oid, nil
+}
+
+// maxBigArcOctets limits the length of a subidentifier parsed by
+// parseBase128BigInt to 140 bits, enough for a 128-bit UUID arc, so that
+// hostile input can't make parsing arbitrarily slow.
+const maxBigArcOctets = 20
+
+func parseBase128BigInt(bytes []byte, offset int) (*big.Int, int, error) {
+	ret := new(big.Int)
+	for start := offset; offset < len(bytes); offset++ {
+		if offset-start == maxBigArcOctets {
+			return nil, offset, errBase128TooLarge
+		}
+		b := bytes[offset]
+		// integers should be minimally encoded, so the leading octet should
+		// never be 0x80
+		if offset == start && b == 0x80 {
+			return nil, offset, errors.New("integer is not minimally encoded")
+		}
+		ret.Lsh(ret, 7)
+		ret.Or(ret, big.NewInt(int64(b&0x7f)))
+		if b&0x80 == 0 {
+			return ret, offset + 1, nil
+		}
+	}
+	return nil, offset, errors.New("truncated base 128 integer")
+}
+
+// encodeBigOID is like encodeOID, but supports arcs of arbitrary size.
+func encodeBigOID(oid []*big.Int) ([]byte, error) {
+	if len(oid) < 2 {
+		return nil, errors.New("OBJECT IDENTIFIER must have at least two arcs")
+	}
+	for i, v := range oid {
+		if v == nil || v.Sign() < 0 {
+			return nil, fmt.Errorf("invalid arc %v in OBJECT IDENTIFIER at position %d", v, i)
+		}
+	}
+	if oid[0].Cmp(big.NewInt(2)) > 0 {
+		return nil, fmt.Errorf("invalid first arc %s in OBJECT IDENTIFIER", oid[0])
+	}
+	if oid[0].Cmp(big.NewInt(2)) < 0 && oid[1].Cmp(big.NewInt(39)) > 0 {
+		return nil, fmt.Errorf("invalid second arc %s in OBJECT IDENTIFIER for first arc %s", oid[1], oid[0])
+	}
+
+	first := new(big.Int).Mul(oid[0], big.NewInt(40))
+	encoded := appendBase128BigInt(nil, first.Add(first, oid[1]))
+	for _, v := range oid[2:] {
+		encoded = appendBase128BigInt(encoded, v)
+	}
+	return encoded, nil
+}
+
+// parseBigOIDString parses a dotted OBJECT IDENTIFIER with arcs of arbitrary
+// size.
+func parseBigOIDString(oidString string) ([]*big.Int, error) {
+	parts := strings.Split(oidString, ".")
+	oid := make([]*big.Int, len(parts))
+	for i, part := range parts {
+		v, ok := new(big.Int).SetString(part, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid OID part '%s'", part)
+		}
+		oid[i] = v
+	}
+	return oid, nil
+}
+
+// bigOIDToString returns the dotted representation of oid, as OIDToString.
+func bigOIDToString(oid []*big.Int) string {
+	parts := make([]string, len(oid))
+	for i, v := range oid {
+		parts[i] = v.String()
+	}
+	return strings.Join(parts, ".")
+}
+
+func appendBase128BigInt(dst []byte, n *big.Int) []byte {
+	if n.Sign() == 0 {
+		return append(dst, 0)
+	}
+
+	mask := big.NewInt(0x7f)
+	for i := (n.BitLen()+6)/7 - 1; i >= 0; i-- {
+		o := byte(new(big.Int).And(new(big.Int).Rsh(n, uint(i*7)), mask).Int64())
+		if i != 0 {
+			o |= 0x80
+		}
+		dst = append(dst, o)
+	}
+	return dst
+}
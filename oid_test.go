@@ -0,0 +1,179 @@
+package ber
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestChildByOID(t *testing.T) {
+	attribute := func(oid, value string) *Packet {
+		p := NewSequence("AttributeTypeAndValue")
+		p.AppendChild(NewOID(ClassUniversal, TypePrimitive, TagObjectIdentifier, oid, "type"))
+		p.AppendChild(NewString(ClassUniversal, TypePrimitive, TagUTF8String, value, "value"))
+		return p
+	}
+
+	name := NewSequence("RelativeDistinguishedName")
+	name.AppendChild(attribute("2.5.4.6", "CH"))
+	name.AppendChild(attribute("2.5.4.3", "example.com"))
+	name.AppendChild(attribute("2.5.4.10", "Example"))
+
+	decoded, err := DecodePacketErr(name.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	child, ok := decoded.ChildByOID(OID{2, 5, 4, 3})
+	if !ok {
+		t.Fatal("expected to find commonName")
+	}
+	if v := child.Children[1].Value; v != "example.com" {
+		t.Errorf("expected commonName example.com, got %v", v)
+	}
+
+	if _, ok := decoded.ChildByOID(OID{2, 5, 4, 30}); ok {
+		t.Error("expected not to find 2.5.4.30")
+	}
+}
+
+func TestOIDEqualAndHasPrefix(t *testing.T) {
+	if !OIDEqual([]int{1, 3, 6, 1}, OID{1, 3, 6, 1}) {
+		t.Error("expected equal OIDs")
+	}
+	if OIDEqual([]int{1, 3, 6, 1}, []int{1, 3, 6}) || OIDEqual([]int{1, 2, 3}, []int{1, 2, 30}) {
+		t.Error("expected different OIDs")
+	}
+
+	for _, tc := range []struct {
+		oid, prefix []int
+		expected    bool
+	}{
+		{[]int{1, 3, 6, 1, 2}, []int{1, 3, 6, 1}, true},
+		{[]int{1, 3, 6, 1, 2}, []int{1, 3, 6, 10}, false},
+		{[]int{1, 2, 30}, []int{1, 2, 3}, false},
+		{[]int{1, 3, 6}, []int{1, 3, 6}, true},
+		{[]int{1, 3}, []int{1, 3, 6}, false},
+		{[]int{1, 3}, nil, true},
+	} {
+		if got := OIDHasPrefix(tc.oid, tc.prefix); got != tc.expected {
+			t.Errorf("OIDHasPrefix(%v, %v): expected %t, got %t", tc.oid, tc.prefix, tc.expected, got)
+		}
+	}
+}
+
+func TestEncodeOIDPacket(t *testing.T) {
+	oid := OID{1, 3, 6, 1, 4, 1, 311, 21, 20}
+	encoded, err := EncodeOIDPacket(oid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := NewOID(ClassUniversal, TypePrimitive, TagObjectIdentifier, "1.3.6.1.4.1.311.21.20", "").Bytes()
+	if !bytes.Equal(expected, encoded) {
+		t.Errorf("expected % X, got % X", expected, encoded)
+	}
+
+	for _, invalid := range []OID{{1}, {3, 5}, {0, 40}, {1, 2, -3}} {
+		if _, err := EncodeOIDPacket(invalid); err == nil {
+			t.Errorf("%v: expected error", []int(invalid))
+		}
+	}
+}
+
+func TestBigOID(t *testing.T) {
+	// UUID f81d4fae-7dec-11d0-a765-00a0c91e6bf6 as OID below 2.25 (ITU-T X.667)
+	uuid, _ := new(big.Int).SetString("329800735698586629295641978511506172918", 10)
+	oid := []*big.Int{big.NewInt(2), big.NewInt(25), uuid}
+
+	encoded, err := encodeBigOID(oid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if encoded[0] != 0x69 || len(encoded) != 20 {
+		t.Errorf("unexpected encoding % X", encoded)
+	}
+	decoded, err := parseObjectIdentifierBig(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decoded) != len(oid) {
+		t.Fatalf("expected %d arcs, got %d", len(oid), len(decoded))
+	}
+	for i := range oid {
+		if decoded[i].Cmp(oid[i]) != 0 {
+			t.Errorf("arc %d: expected %s, got %s", i, oid[i], decoded[i])
+		}
+	}
+	if _, err := parseObjectIdentifier(encoded); err == nil {
+		t.Error("expected the int based parser to reject the large arc")
+	}
+
+	// small OIDs encode as with encodeOID
+	for _, s := range []string{"1.3.6.1.4.1.311.21.20", "2.100.3", "0.39"} {
+		arcs, _ := parseOIDString(s)
+		bigArcs := make([]*big.Int, len(arcs))
+		for i, v := range arcs {
+			bigArcs[i] = big.NewInt(int64(v))
+		}
+		expected, _ := encodeOID(s)
+		if got, err := encodeBigOID(bigArcs); err != nil || !bytes.Equal(expected, got) {
+			t.Errorf("%s: expected % X, got % X (%v)", s, expected, got, err)
+		}
+	}
+
+	if _, err := encodeBigOID([]*big.Int{big.NewInt(1), big.NewInt(40)}); err == nil {
+		t.Error("expected error for invalid second arc")
+	}
+	if _, err := parseObjectIdentifierBig([]byte{0x2a, 0x86}); err == nil {
+		t.Error("expected error for truncated arc")
+	}
+}
+
+func TestBigOIDPacket(t *testing.T) {
+	// UUID f81d4fae-7dec-11d0-a765-00a0c91e6bf6 as OID below 2.25 (ITU-T X.667)
+	const oid = "2.25.329800735698586629295641978511506172918"
+	uuid, _ := new(big.Int).SetString("329800735698586629295641978511506172918", 10)
+	expected, err := encodeBigOID([]*big.Int{big.NewInt(2), big.NewInt(25), uuid})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := NewOID(ClassUniversal, TypePrimitive, TagObjectIdentifier, oid, "")
+	if p == nil {
+		t.Fatal("expected NewOID to encode the large arc")
+	}
+	if !bytes.Equal(expected, p.Data.Bytes()) {
+		t.Errorf("expected % X, got % X", expected, p.Data.Bytes())
+	}
+
+	decoded, err := DecodePacketErr(p.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.Value != oid {
+		t.Errorf("expected %s, got %v", oid, decoded.Value)
+	}
+}
+
+func TestBigOIDLongArc(t *testing.T) {
+	// 2.25 followed by an arc of 400001 octets
+	content := append([]byte{0x69}, bytes.Repeat([]byte{0xFF}, 400000)...)
+	content = append(content, 0x7F)
+	if _, err := parseObjectIdentifierBig(content); err == nil {
+		t.Error("expected error for an arc longer than maxBigArcOctets")
+	}
+
+	data := append([]byte{0x06, 0x83, 0x06, 0x1A, 0x82}, content...)
+	start := time.Now()
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Value != nil {
+		t.Errorf("expected no value for an oversized arc, got %.20v", p.Value)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("decoding took %s", elapsed)
+	}
+}
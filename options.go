@@ -0,0 +1,82 @@
+package ber
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+// ErrDecodeTimeout is returned by DecodePacketTimeout when decoding takes longer than allowed.
+var ErrDecodeTimeout = errors.New("decode timed out")
+
+// DecodeOptions control the behavior of DecodePacketOptions.
+type DecodeOptions struct {
+	// BestEffort returns the partially decoded tree together with the error
+	// when decoding fails part way, instead of discarding everything. Children
+	// decoded before the error are kept, the failing child is included as far
+	// as it could be decoded.
+	BestEffort bool
+	// Strict rejects encodings that are valid BER but not DER, e.g. a BOOLEAN
+	// true encoded as anything but 0xFF or an INTEGER with redundant leading
+	// octets.
+	Strict bool
+	// MaxStringLength limits the content length of every universal string
+	// value, e.g. an OCTET STRING, independently of MaxPacketLengthBytes. The
+	// segments of a constructed string are limited by their total length.
+	// Zero means no limit.
+	MaxStringLength int
+	// MaxTotalAllocation limits the bytes held by the whole decoded tree,
+	// counting the content of every primitive packet and the child encodings
+	// copied into the Data of every constructed packet. Unlike the per-packet
+	// limits it catches many small values adding up to a large allocation.
+	// Zero means no limit.
+	MaxTotalAllocation int
+	// ApplicationDecoders set the Value of primitive [APPLICATION tag] packets
+	// by tag, e.g. DecodeSMIIPAddress for the SMI IpAddress. An error returned
+	// by a decoder is a decode error. Application tags are defined per
+	// protocol, so packets without a decoder keep a nil Value.
+	ApplicationDecoders map[Tag]ApplicationDecoder
+}
+
+// ApplicationDecoder returns the Value of a primitive application-class packet
+// decoded from its content octets.
+type ApplicationDecoder func(content []byte) (interface{}, error)
+
+// decodeApplicationValue sets the Value of the primitive application-class
+// packet p using the decoder given for its tag in decoders, if any.
+func decodeApplicationValue(p *Packet, content []byte, decoders map[Tag]ApplicationDecoder) error {
+	decode := decoders[p.Tag]
+	if decode == nil {
+		return nil
+	}
+	v, err := decode(content)
+	if err != nil {
+		return err
+	}
+	p.Value = v
+	return nil
+}
+
+// DecodePacketOptions decodes the given bytes into a single Packet using the
+// given options. Unless opts.BestEffort is set, nil is returned together with
+// any decode error.
+func DecodePacketOptions(data []byte, opts DecodeOptions) (*Packet, error) {
+	d := &decoder{opts: opts}
+	p, _, err := d.readPacket(bytes.NewBuffer(data))
+	if err != nil {
+		return d.partial(p), err
+	}
+	return p, nil
+}
+
+// DecodePacketTimeout decodes the given bytes into a single Packet, aborting
+// with ErrDecodeTimeout if decoding takes longer than timeout. The deadline is
+// checked before decoding each element of the tree.
+func DecodePacketTimeout(data []byte, timeout time.Duration) (*Packet, error) {
+	d := &decoder{deadline: time.Now().Add(timeout)}
+	p, _, err := d.readPacket(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,178 @@
+package ber
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestDecodePacketOptionsBestEffort(t *testing.T) {
+	// SEQUENCE { INTEGER 1, INTEGER 2, OCTET STRING truncated after one of five bytes }
+	data := []byte{0x30, 0x0b, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x04, 0x05, 'a'}
+
+	p, err := DecodePacketOptions(data, DecodeOptions{})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected UnexpectedEOF, got %v", err)
+	}
+	if p != nil {
+		t.Error("expected no packet without best effort")
+	}
+
+	p, err = DecodePacketOptions(data, DecodeOptions{BestEffort: true})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected UnexpectedEOF, got %v", err)
+	}
+	if p == nil {
+		t.Fatal("expected partial packet with best effort")
+	}
+	if len(p.Children) != 2 {
+		t.Fatalf("expected 2 children, got %d", len(p.Children))
+	}
+	for i, child := range p.Children {
+		if v, ok := child.Value.(int64); !ok || v != int64(i+1) {
+			t.Errorf("child %d: expected %d, got %v", i, i+1, child.Value)
+		}
+	}
+}
+
+func TestDecodePacketTimeout(t *testing.T) {
+	data := NewString(ClassUniversal, TypePrimitive, TagOctetString, "value", "").Bytes()
+	if _, err := DecodePacketTimeout(data, time.Second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// 100000 nested indefinite-length sequences
+	const depth = 100000
+	nested := make([]byte, 0, depth*4)
+	for i := 0; i < depth; i++ {
+		nested = append(nested, 0x30, 0x80)
+	}
+	for i := 0; i < depth; i++ {
+		nested = append(nested, 0x00, 0x00)
+	}
+
+	if _, err := DecodePacketTimeout(nested, time.Microsecond); err != ErrDecodeTimeout {
+		t.Errorf("expected ErrDecodeTimeout, got %v", err)
+	}
+}
+
+func TestDecodePacketOptionsStrictBoolean(t *testing.T) {
+	raw := NewRawBoolean(ClassUniversal, TypePrimitive, TagBoolean, []byte{0x00, 0xFF}, "two-octet boolean")
+	if !bytes.Equal([]byte{0x01, 0x02, 0x00, 0xFF}, raw.Bytes()) {
+		t.Errorf("unexpected encoding % X", raw.Bytes())
+	}
+
+	if _, err := DecodePacketOptions(raw.Bytes(), DecodeOptions{}); err != nil {
+		t.Errorf("unexpected error without strict mode: %v", err)
+	}
+	if _, err := DecodePacketOptions(raw.Bytes(), DecodeOptions{Strict: true}); err == nil {
+		t.Error("expected strict mode to reject a two-octet boolean")
+	}
+
+	for _, tc := range []struct {
+		p     *Packet
+		valid bool
+	}{
+		{NewRawBoolean(ClassUniversal, TypePrimitive, TagBoolean, []byte{0xFF}, ""), true},
+		{NewLDAPBoolean(ClassUniversal, TypePrimitive, TagBoolean, false, ""), true},
+		{NewLDAPBoolean(ClassUniversal, TypePrimitive, TagBoolean, true, ""), false},
+		{NewBoolean(ClassUniversal, TypePrimitive, TagBoolean, true, ""), false},
+	} {
+		_, err := DecodePacketOptions(tc.p.Bytes(), DecodeOptions{Strict: true})
+		if tc.valid && err != nil {
+			t.Errorf("% X: unexpected error: %v", tc.p.Bytes(), err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("% X: expected error", tc.p.Bytes())
+		}
+	}
+}
+
+func TestDecodePacketOptionsMaxStringLength(t *testing.T) {
+	sequence := NewSequence("")
+	sequence.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "short", ""))
+	sequence.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "much too long", ""))
+	sequence.AppendChild(Integer(1234567890))
+	data := sequence.Bytes()
+
+	opts := DecodeOptions{MaxStringLength: 8}
+	if _, err := DecodePacketOptions(data, opts); err == nil || err.Error() != "string length 13 greater than maximum 8" {
+		t.Errorf("expected string length error, got %v", err)
+	}
+
+	// non-string values are not limited
+	valid := NewSequence("")
+	valid.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "short", ""))
+	valid.AppendChild(Integer(1234567890))
+	p, err := DecodePacketOptions(valid.Bytes(), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Children) != 2 {
+		t.Errorf("expected 2 children, got %d", len(p.Children))
+	}
+
+	if _, err := DecodePacketOptions(data, DecodeOptions{}); err != nil {
+		t.Errorf("unexpected error without limit: %v", err)
+	}
+}
+
+func TestDecodePacketOptionsMaxTotalAllocation(t *testing.T) {
+	// 100 strings of 1000 bytes, each within the per-string limit
+	value := string(bytes.Repeat([]byte{'x'}, 1000))
+	sequence := NewSequence("")
+	for i := 0; i < 100; i++ {
+		sequence.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, value, ""))
+	}
+	data := sequence.Bytes()
+
+	opts := DecodeOptions{MaxStringLength: 2000, MaxTotalAllocation: 64 * 1024}
+	if _, err := DecodePacketOptions(data, opts); err == nil || err.Error() != "total allocation exceeds maximum 65536" {
+		t.Errorf("expected total allocation error, got %v", err)
+	}
+
+	opts.MaxTotalAllocation = 256 * 1024
+	p, err := DecodePacketOptions(data, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Children) != 100 {
+		t.Errorf("expected 100 children, got %d", len(p.Children))
+	}
+
+	// every level of nesting holds another copy of the string
+	nested := NewString(ClassUniversal, TypePrimitive, TagOctetString, value, "")
+	for i := 0; i < 10; i++ {
+		outer := NewSequence("")
+		outer.AppendChild(nested)
+		nested = outer
+	}
+	if _, err := DecodePacketOptions(nested.Bytes(), DecodeOptions{MaxTotalAllocation: 5000}); err == nil {
+		t.Error("expected total allocation error for nested copies")
+	}
+
+	if _, err := DecodePacketOptions(data, DecodeOptions{}); err != nil {
+		t.Errorf("unexpected error without limit: %v", err)
+	}
+}
+
+func TestDecodePacketOptionsMaxStringLengthConstructed(t *testing.T) {
+	// OCTET STRING built from 10 segments of 4 bytes, each within the limit
+	str := Encode(ClassUniversal, TypeConstructed, TagOctetString, nil, "")
+	for i := 0; i < 10; i++ {
+		str.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "abcd", ""))
+	}
+	data := str.Bytes()
+
+	if _, err := DecodePacketOptions(data, DecodeOptions{MaxStringLength: 39}); err == nil || err.Error() != "string length 40 greater than maximum 39" {
+		t.Errorf("expected string length error, got %v", err)
+	}
+	p, err := DecodePacketOptions(data, DecodeOptions{MaxStringLength: 40})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Value.(string)) != 40 {
+		t.Errorf("expected 40 bytes, got %d", len(p.Value.(string)))
+	}
+}
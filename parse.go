@@ -0,0 +1,84 @@
+package ber
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Handler receives the events produced by Parse.
+type Handler interface {
+	// StartElement is called for every TLV. length is LengthIndefinite for
+	// constructed elements encoded with indefinite length.
+	StartElement(class Class, typ Type, tag Tag, length int)
+	// PrimitiveValue is called with the content octets of a primitive element.
+	PrimitiveValue(value []byte)
+	// EndElement is called once all content of the current element was reported.
+	EndElement()
+}
+
+// Parse decodes the single packet in data as a stream of events sent to h,
+// without building the packet tree. End-of-contents markers terminating
+// indefinite-length elements are not reported.
+func Parse(data []byte, h Handler) error {
+	_, _, err := parseElement(bytes.NewReader(data), h, false, 0)
+	return err
+}
+
+// parseElement reports a single element to h, returning the number of bytes
+// read and whether the element was an end-of-contents marker terminating an
+// indefinite-length parent.
+func parseElement(reader io.Reader, h Handler, inIndefinite bool, depth int) (int, bool, error) {
+	identifier, length, read, err := readHeader(reader)
+	if err != nil {
+		return read, false, err
+	}
+
+	if inIndefinite && length == 0 && identifier == (Identifier{ClassType: ClassUniversal, TagType: TypePrimitive, Tag: TagEOC}) {
+		return read, true, nil
+	}
+
+	h.StartElement(identifier.ClassType, identifier.TagType, identifier.Tag, length)
+
+	if identifier.TagType == TypeConstructed {
+		depth++
+		if MaxPacketDepth > 0 && depth > MaxPacketDepth {
+			return read, false, ErrMaxDepthExceeded
+		}
+		contentRead := 0
+		for length == LengthIndefinite || contentRead < length {
+			r, eoc, err := parseElement(reader, h, length == LengthIndefinite, depth)
+			if err != nil {
+				return read, false, unexpectedEOF(err)
+			}
+			contentRead += r
+			read += r
+			if eoc {
+				break
+			}
+		}
+		if length != LengthIndefinite && contentRead > length {
+			return read, false, fmt.Errorf("expected to read %d bytes, read %d", length, contentRead)
+		}
+		h.EndElement()
+		return read, false, nil
+	}
+
+	if MaxPacketLengthBytes > 0 && int64(length) > MaxPacketLengthBytes {
+		return read, false, fmt.Errorf("length %d greater than maximum %d", length, MaxPacketLengthBytes)
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(reader, int64(length)))
+	if err == nil && len(content) < length {
+		err = io.EOF
+	}
+	if err != nil {
+		return read, false, unexpectedEOF(err)
+	}
+	read += len(content)
+
+	h.PrimitiveValue(content)
+	h.EndElement()
+	return read, false, nil
+}
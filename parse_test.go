@@ -0,0 +1,53 @@
+package ber
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type recordingHandler struct {
+	events []string
+}
+
+func (h *recordingHandler) StartElement(class Class, typ Type, tag Tag, length int) {
+	h.events = append(h.events, fmt.Sprintf("start %s %s %d len=%d", ClassMap[class], TypeMap[typ], tag, length))
+}
+
+func (h *recordingHandler) PrimitiveValue(value []byte) {
+	h.events = append(h.events, fmt.Sprintf("value % X", value))
+}
+
+func (h *recordingHandler) EndElement() {
+	h.events = append(h.events, "end")
+}
+
+func TestParse(t *testing.T) {
+	// SEQUENCE { INTEGER 1, SEQUENCE (indefinite) { OCTET STRING "ab" } }
+	data := []byte{0x30, 0x0b, 0x02, 0x01, 0x01, 0x30, 0x80, 0x04, 0x02, 'a', 'b', 0x00, 0x00}
+
+	h := &recordingHandler{}
+	if err := Parse(data, h); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"start Universal Constructed 16 len=11",
+		"start Universal Primitive 2 len=1",
+		"value 01",
+		"end",
+		"start Universal Constructed 16 len=-1",
+		"start Universal Primitive 4 len=2",
+		"value 61 62",
+		"end",
+		"end",
+		"end",
+	}
+	if !reflect.DeepEqual(expected, h.events) {
+		t.Errorf("unexpected events\nwant: %q\ngot:  %q", expected, h.events)
+	}
+
+	if err := Parse(data[:8], &recordingHandler{}); err == nil {
+		t.Error("expected error parsing truncated data")
+	}
+}
@@ -0,0 +1,62 @@
+package ber
+
+import (
+	"fmt"
+	"time"
+)
+
+// NewValidity returns the Validity of an X.509 certificate (RFC 5280,
+// 4.1.2.5):
+//
+//	Validity ::= SEQUENCE {
+//	    notBefore Time,
+//	    notAfter  Time }
+//
+// Dates in 1950 through 2049 are encoded as UTCTime, all others as
+// GeneralizedTime.
+func NewValidity(notBefore, notAfter time.Time) *Packet {
+	p := NewSequence("Validity")
+	p.AppendChild(newPKIXTime(notBefore, "notBefore"))
+	p.AppendChild(newPKIXTime(notAfter, "notAfter"))
+	return p
+}
+
+func newPKIXTime(t time.Time, description string) *Packet {
+	// Both forms are in UTC without fractional seconds (RFC 5280, 4.1.2.5)
+	t = t.UTC().Truncate(time.Second)
+	if year := t.Year(); year >= 1950 && year < 2050 {
+		return NewUTCTime(ClassUniversal, TypePrimitive, TagUTCTime, t, description)
+	}
+	return NewGeneralizedTime(ClassUniversal, TypePrimitive, TagGeneralizedTime, t, description)
+}
+
+// ParseValidity extracts notBefore and notAfter from a decoded Validity, see
+// NewValidity.
+func ParseValidity(p *Packet) (notBefore, notAfter time.Time, err error) {
+	if err := expectSequence(p, "Validity", 2); err != nil {
+		return zeroTime, zeroTime, err
+	}
+
+	times := make([]time.Time, 2)
+	for i, child := range p.Children {
+		t, ok := child.Value.(time.Time)
+		if child.ClassType != ClassUniversal || (child.Tag != TagUTCTime && child.Tag != TagGeneralizedTime) || !ok {
+			return zeroTime, zeroTime, fmt.Errorf("validity element %d is not a UTCTime or GeneralizedTime", i)
+		}
+		times[i] = t
+	}
+	return times[0], times[1], nil
+}
+
+// NewAlgorithmIdentifierNull returns an AlgorithmIdentifier with NULL
+// parameters, as used by e.g. the RSA signature algorithms (RFC 5280, 4.1.1.2):
+//
+//	AlgorithmIdentifier ::= SEQUENCE {
+//	    algorithm  OBJECT IDENTIFIER,
+//	    parameters ANY DEFINED BY algorithm OPTIONAL }
+func NewAlgorithmIdentifierNull(oid OID) *Packet {
+	p := NewSequence("AlgorithmIdentifier")
+	p.AppendChild(NewOID(ClassUniversal, TypePrimitive, TagObjectIdentifier, oid.String(), "algorithm"))
+	p.AppendChild(Encode(ClassUniversal, TypePrimitive, TagNULL, nil, "parameters"))
+	return p
+}
@@ -0,0 +1,99 @@
+package ber
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestValidity(t *testing.T) {
+	for _, tc := range []struct {
+		notBefore, notAfter time.Time
+		beforeTag, afterTag Tag
+	}{
+		{
+			time.Date(2049, time.December, 31, 23, 59, 59, 0, time.UTC),
+			time.Date(2050, time.January, 1, 0, 0, 0, 0, time.UTC),
+			TagUTCTime, TagGeneralizedTime,
+		},
+		{
+			time.Date(1949, time.December, 31, 23, 59, 59, 0, time.UTC),
+			time.Date(1950, time.January, 1, 0, 0, 0, 0, time.UTC),
+			TagGeneralizedTime, TagUTCTime,
+		},
+		{
+			// 2049-12-31T23:30:00Z, local midnight of 2050 in UTC+1
+			time.Date(2050, time.January, 1, 0, 30, 0, 0, time.FixedZone("", 3600)),
+			time.Date(9999, time.December, 31, 23, 59, 59, 0, time.UTC),
+			TagUTCTime, TagGeneralizedTime,
+		},
+	} {
+		p, err := DecodePacketErr(NewValidity(tc.notBefore, tc.notAfter).Bytes())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.Children[0].Tag != tc.beforeTag || p.Children[1].Tag != tc.afterTag {
+			t.Errorf("%s - %s: expected tags %d and %d, got %d and %d", tc.notBefore, tc.notAfter,
+				tc.beforeTag, tc.afterTag, p.Children[0].Tag, p.Children[1].Tag)
+		}
+
+		notBefore, notAfter, err := ParseValidity(p)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !notBefore.Equal(tc.notBefore) || !notAfter.Equal(tc.notAfter) {
+			t.Errorf("expected %s - %s, got %s - %s", tc.notBefore, tc.notAfter, notBefore, notAfter)
+		}
+	}
+
+	if _, _, err := ParseValidity(NewSequence("")); err == nil {
+		t.Error("expected error for an empty sequence")
+	}
+}
+
+func TestValidityFractionalSeconds(t *testing.T) {
+	notBefore := time.Date(2020, time.March, 1, 12, 0, 0, 123456789, time.UTC)
+	notAfter := time.Date(2060, time.March, 1, 12, 0, 0, 987654321, time.UTC)
+	p := NewValidity(notBefore, notAfter)
+
+	expected := []byte("20600301120000Z")
+	if !bytes.Equal(expected, p.Children[1].Data.Bytes()) {
+		t.Errorf("expected GeneralizedTime %s, got %s", expected, p.Children[1].Data.Bytes())
+	}
+
+	decoded, err := DecodePacketErr(p.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decodedBefore, decodedAfter, err := ParseValidity(decoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decodedBefore.Equal(notBefore.Truncate(time.Second)) || !decodedAfter.Equal(notAfter.Truncate(time.Second)) {
+		t.Errorf("expected whole seconds, got %s - %s", decodedBefore, decodedAfter)
+	}
+}
+
+func TestNewAlgorithmIdentifierNull(t *testing.T) {
+	sha256WithRSAEncryption := OID{1, 2, 840, 113549, 1, 1, 11}
+	expected := []byte{0x30, 0x0d, 0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x01, 0x01, 0x0b, 0x05, 0x00}
+
+	p := NewAlgorithmIdentifierNull(sha256WithRSAEncryption)
+	if !bytes.Equal(expected, p.Bytes()) {
+		t.Errorf("expected % X, got % X", expected, p.Bytes())
+	}
+
+	decoded, err := DecodePacketErr(p.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decoded.Children) != 2 {
+		t.Fatalf("expected 2 elements, got %d", len(decoded.Children))
+	}
+	if v := decoded.Children[0].Value; v != sha256WithRSAEncryption.String() {
+		t.Errorf("expected algorithm %s, got %v", sha256WithRSAEncryption, v)
+	}
+	if params := decoded.Children[1]; params.Tag != TagNULL || params.Data.Len() != 0 {
+		t.Errorf("expected NULL parameters, got %s", DescribePacket(params))
+	}
+}
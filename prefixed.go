@@ -0,0 +1,102 @@
+package ber
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+)
+
+// PrefixedReader reads BER payloads each preceded by a fixed-size big-endian
+// length, as used by transports without S101 framing.
+type PrefixedReader struct {
+	r      io.Reader
+	prefix []byte
+}
+
+// LengthPrefixedReader returns a PrefixedReader reading payloads from r, each
+// preceded by a length of prefixBytes octets. It panics if prefixBytes is not
+// between 1 and 8.
+func LengthPrefixedReader(r io.Reader, prefixBytes int) *PrefixedReader {
+	if prefixBytes < 1 || prefixBytes > 8 {
+		panic(fmt.Sprintf("ber: invalid length prefix size %d", prefixBytes))
+	}
+	return &PrefixedReader{r: r, prefix: make([]byte, prefixBytes)}
+}
+
+// ReadPayload returns the raw BER payload of the next frame. io.EOF is
+// returned if r ends before a frame, io.ErrUnexpectedEOF if it ends within
+// one. Payloads longer than MaxPacketLengthBytes are rejected before reading
+// them. The payload buffer grows as data arrives, so a large length prefix
+// doesn't allocate more than what r actually delivers.
+func (pr *PrefixedReader) ReadPayload() ([]byte, error) {
+	if _, err := io.ReadFull(pr.r, pr.prefix); err != nil {
+		return nil, err
+	}
+	var length uint64
+	for _, b := range pr.prefix {
+		length = length<<8 | uint64(b)
+	}
+	if MaxPacketLengthBytes > 0 && length > uint64(MaxPacketLengthBytes) {
+		return nil, fmt.Errorf("length %d greater than maximum %d", length, MaxPacketLengthBytes)
+	}
+	if length > math.MaxInt64 {
+		return nil, fmt.Errorf("length %d greater than maximum %d", length, int64(math.MaxInt64))
+	}
+
+	var payload bytes.Buffer
+	if _, err := io.CopyN(&payload, pr.r, int64(length)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return payload.Bytes(), nil
+}
+
+// ReadPacket reads the next frame and decodes its payload, which must hold
+// exactly one packet.
+func (pr *PrefixedReader) ReadPacket() (*Packet, error) {
+	payload, err := pr.ReadPayload()
+	if err != nil {
+		return nil, err
+	}
+	p := new(Packet)
+	if err := p.UnmarshalBinary(payload); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PrefixedWriter writes BER payloads each preceded by a fixed-size big-endian
+// length, the counterpart of PrefixedReader.
+type PrefixedWriter struct {
+	w           io.Writer
+	prefixBytes int
+}
+
+// LengthPrefixedWriter returns a PrefixedWriter writing to w with a length of
+// prefixBytes octets. It panics if prefixBytes is not between 1 and 8.
+func LengthPrefixedWriter(w io.Writer, prefixBytes int) *PrefixedWriter {
+	if prefixBytes < 1 || prefixBytes > 8 {
+		panic(fmt.Sprintf("ber: invalid length prefix size %d", prefixBytes))
+	}
+	return &PrefixedWriter{w: w, prefixBytes: prefixBytes}
+}
+
+// WritePayload writes payload as a single frame. An error is returned if its
+// length doesn't fit the prefix.
+func (pw *PrefixedWriter) WritePayload(payload []byte) error {
+	length := uint64(len(payload))
+	if pw.prefixBytes < 8 && length >= 1<<uint(8*pw.prefixBytes) {
+		return fmt.Errorf("payload length %d exceeds %d byte length prefix", length, pw.prefixBytes)
+	}
+	frame := make([]byte, pw.prefixBytes, pw.prefixBytes+len(payload))
+	for i := range frame {
+		frame[i] = byte(length >> uint(8*(pw.prefixBytes-1-i)))
+	}
+	_, err := pw.w.Write(append(frame, payload...))
+	return err
+}
+
+// WritePacket writes the encoding of p as a single frame.
+func (pw *PrefixedWriter) WritePacket(p *Packet) error {
+	return pw.WritePayload(p.Bytes())
+}
@@ -0,0 +1,87 @@
+package ber
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"testing"
+)
+
+func TestLengthPrefixed(t *testing.T) {
+	packets := []*Packet{
+		NewLDAPResult(0, "cn=admin", ""),
+		String(string(make([]byte, 300))),
+		Integer(-1),
+	}
+
+	var buf bytes.Buffer
+	w := LengthPrefixedWriter(&buf, 4)
+	for _, p := range packets {
+		if err := w.WritePacket(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	data := buf.Bytes()
+	if prefix := data[:4]; !bytes.Equal([]byte{0x00, 0x00, 0x00, byte(packets[0].ByteLen())}, prefix) {
+		t.Errorf("unexpected length prefix % X", prefix)
+	}
+
+	r := LengthPrefixedReader(bytes.NewReader(data), 4)
+	for i, expected := range packets {
+		p, err := r.ReadPacket()
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if !p.Equal(expected) {
+			t.Errorf("%d: expected % X, got % X", i, expected.Bytes(), p.Bytes())
+		}
+	}
+	if _, err := r.ReadPayload(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	if _, err := LengthPrefixedReader(bytes.NewReader(data[:len(data)-1]), 4).ReadPayload(); err != nil {
+		t.Errorf("unexpected error for the complete first frame: %v", err)
+	}
+	truncated := LengthPrefixedReader(bytes.NewReader(data[:10]), 4)
+	if _, err := truncated.ReadPayload(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if _, err := LengthPrefixedReader(bytes.NewReader([]byte{0x00, 0x00}), 4).ReadPayload(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF for a truncated prefix, got %v", err)
+	}
+	if _, err := LengthPrefixedReader(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF}), 4).ReadPayload(); err == nil {
+		t.Error("expected error for a length above MaxPacketLengthBytes")
+	}
+	if err := LengthPrefixedWriter(&buf, 1).WritePayload(make([]byte, 256)); err == nil {
+		t.Error("expected error for a payload exceeding a 1 byte prefix")
+	}
+}
+
+func TestLengthPrefixedLargePrefix(t *testing.T) {
+	// a 2 GiB length prefix followed by only 3 bytes
+	data := []byte{0x7F, 0xFF, 0xFF, 0xFF, 0x04, 0x01, 'a'}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := LengthPrefixedReader(bytes.NewReader(data), 4).ReadPayload()
+	runtime.ReadMemStats(&after)
+
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("expected the allocation to follow the data read, allocated %d bytes", allocated)
+	}
+}
+
+func TestLengthPrefixedLengthOverflow(t *testing.T) {
+	defer func(max int64) { MaxPacketLengthBytes = max }(MaxPacketLengthBytes)
+	MaxPacketLengthBytes = 0
+
+	// a length of 2^63 doesn't fit in an int64
+	data := []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0x01, 'a'}
+	if p, err := LengthPrefixedReader(bytes.NewReader(data), 8).ReadPayload(); err == nil {
+		t.Errorf("expected error for a length above math.MaxInt64, got % X", p)
+	}
+}
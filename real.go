@@ -36,6 +36,35 @@ func encodeFloat(v float64) []byte {
 	}
 }
 
+// encodeFloatDER returns the DER encoding of v (x.690, 11.3.1). The special
+// values and zero are encoded as by encodeFloat, all other values in binary
+// form with base 2, a scaling factor of 0 and an odd mantissa N in the minimal
+// number of unsigned octets.
+func encodeFloatDER(v float64) []byte {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return encodeFloat(v)
+	}
+	info := byte(0x80)
+	if v < 0 {
+		info |= 0x40
+		v = -v
+	}
+	frac, exp := math.Frexp(v)
+	mantissa, exponent := uint64(math.Ldexp(frac, 53)), int64(exp-53)
+	for mantissa&1 == 0 {
+		mantissa >>= 1
+		exponent++
+	}
+	e := minimalTwosComplement(exponent)
+	info |= byte(len(e) - 1)
+	out := append([]byte{info}, e...)
+	return append(out, encodeUnsignedInteger(mantissa)...)
+}
+
+// ParseReal decodes the content octets of a REAL (x.690, 8.5). It handles the
+// empty encoding of +0, the special values, binary encodings in base 2, 8 and
+// 16, and the ISO 6093 decimal forms. Truncated or otherwise malformed content
+// results in an error.
 func ParseReal(v []byte) (val float64, err error) {
 	if len(v) == 0 {
 		return 0.0, nil
@@ -60,25 +89,90 @@ func ParseReal(v []byte) (val float64, err error) {
 	return val, nil
 }
 
+// RealEncodingsEquivalent reports whether the REAL content octets a and b
+// decode to the same value. Unlike a byte comparison, this treats the
+// EmberLib encoding, which stores a negative mantissa in two's complement
+// instead of setting the sign bit, as equivalent to the conformant encoding
+// with the sign bit set. Two NaNs are considered equivalent, +0 and -0 are not.
+func RealEncodingsEquivalent(a, b []byte) bool {
+	va, err := ParseReal(a)
+	if err != nil {
+		return false
+	}
+	vb, err := ParseReal(b)
+	if err != nil {
+		return false
+	}
+	if realsEqual(va, vb) {
+		return true
+	}
+	if ea, ok := emberLibReal(a); ok && len(b) > 0 && b[0]&0xC0 == 0xC0 && realsEqual(ea, vb) {
+		return true
+	}
+	if eb, ok := emberLibReal(b); ok && len(a) > 0 && a[0]&0xC0 == 0xC0 && realsEqual(va, eb) {
+		return true
+	}
+	return false
+}
+
+func realsEqual(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return a == b && math.Signbit(a) == math.Signbit(b)
+}
+
+// emberLibReal decodes v as written by EmberLib, which stores a negative
+// mantissa in two's complement and leaves the sign bit clear. ok is false
+// unless v is a binary encoding in that form, i.e. with the sign bit clear and
+// the high bit of the mantissa set.
+func emberLibReal(v []byte) (float64, bool) {
+	if len(v) < 2 || v[0]&0xC0 != 0x80 {
+		return 0, false
+	}
+	offset := 1 + int(v[0]&0x03) + 1
+	if v[0]&0x03 == 0x03 {
+		offset = 2 + int(v[1])
+	}
+	if offset >= len(v) || v[offset]&0x80 == 0 {
+		return 0, false
+	}
+
+	// Negate the two's complement mantissa and set the sign bit instead
+	conformant := append([]byte{v[0] | 0x40}, v[1:]...)
+	mantissa := conformant[offset:]
+	carry := true
+	for i := len(mantissa) - 1; i >= 0; i-- {
+		mantissa[i] ^= 0xFF
+		if carry {
+			mantissa[i]++
+			carry = mantissa[i] == 0
+		}
+	}
+	val, err := ParseReal(conformant)
+	return val, err == nil
+}
+
 func parseBinaryFloat(v []byte) (float64, error) {
 	var info byte
 	var buf []byte
 
 	info, v = v[0], v[1:]
 
-	var base int
+	// The exponent counts digits of the base, i.e. 1, 3 or 4 bits (x.690, 8.5.7.2)
+	var baseBits int64
 	switch info & 0x30 {
 	case 0x00:
-		base = 2
+		baseBits = 1
 	case 0x10:
-		base = 8
+		baseBits = 3
 	case 0x20:
-		base = 16
+		baseBits = 4
 	case 0x30:
 		return 0.0, errors.New("bits 6 and 5 of information octet for REAL are equal to 11")
 	}
 
-	scale := uint((info & 0x0c) >> 2)
+	scale := int64((info & 0x0c) >> 2)
 
 	var expLen int
 	switch info & 0x03 {
@@ -111,17 +205,31 @@ func parseBinaryFloat(v []byte) (float64, error) {
 		return 0.0, errors.New("too big value of mantissa")
 	}
 
-	mant, err := ParseInt64(v)
+	// N is unsigned, the sign is given by bit 7 of the information octet (x.690, 8.5.7.5)
+	mant, err := ParseUint64(v)
 	if err != nil {
 		return 0.0, err
 	}
-	mantissa := mant << scale
-
+	// Zero is only encoded with an empty value block (x.690, 8.5.2) or as the
+	// special value -0, a binary encoding always has a non-zero mantissa
+	if mant == 0 {
+		return 0.0, errors.New("mantissa of binary REAL must not be zero")
+	}
+	mantissa := float64(mant)
 	if info&0x40 == 0x40 {
 		mantissa = -mantissa
 	}
 
-	return float64(mantissa) * math.Pow(float64(base), float64(exponent)), nil
+	// M = S * N * 2^F, independent of the base (x.690, 8.5.7.3), so the value
+	// is N * 2^(F + E * bits per digit). Exponents this far out of range of a
+	// float64 give ±Inf or 0 anyway, limiting them avoids overflowing the sum.
+	const maxExponent = 1 << 16
+	if exponent > maxExponent {
+		exponent = maxExponent
+	} else if exponent < -maxExponent {
+		exponent = -maxExponent
+	}
+	return math.Ldexp(mantissa, int(scale+exponent*baseBits)), nil
 }
 
 func parseDecimalFloat(v []byte) (val float64, err error) {
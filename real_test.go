@@ -1,6 +1,7 @@
 package ber
 
 import (
+	"bytes"
 	"math"
 	"testing"
 )
@@ -29,3 +30,400 @@ func TestRealEncoding(t *testing.T) {
 		}
 	}
 }
+
+func TestRealEncodingsEquivalent(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		a, b     []byte
+		expected bool
+	}{
+		// -1.5 = -3 * 2^-1, sign bit set vs. two's complement mantissa (EmberLib)
+		{"negative EmberLib", []byte{0xC0, 0xFF, 0x03}, []byte{0x80, 0xFF, 0xFD}, true},
+		// -96 = -3 * 2^5
+		{"negative EmberLib positive exponent", []byte{0xC0, 0x05, 0x03}, []byte{0x80, 0x05, 0xFD}, true},
+		// 1.5 as binary and as decimal NR3
+		{"binary and decimal", []byte{0x80, 0xFF, 0x03}, encodeFloat(1.5), true},
+		{"identical", encodeFloat(0.15625), encodeFloat(0.15625), true},
+		{"NaN", encodeFloat(math.NaN()), encodeFloat(math.NaN()), true},
+		{"different values", []byte{0xC0, 0xFF, 0x03}, []byte{0x80, 0xFF, 0x03}, false},
+		// 128, conformant with the high bit of N set, with and without a leading zero octet
+		{"mantissa high bit", []byte{0x80, 0x00, 0x80}, []byte{0x80, 0x00, 0x00, 0x80}, true},
+		{"mantissa high bit decimal", []byte{0x80, 0x00, 0x80}, encodeFloat(128), true},
+		{"zero sign", encodeFloat(0), encodeFloat(negativeZero), false},
+		{"invalid", []byte{0xC0, 0xFF, 0x03}, []byte{0x42, 0x00}, false},
+	} {
+		if got := RealEncodingsEquivalent(tc.a, tc.b); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v (% X <=> % X)", tc.name, tc.expected, got, tc.a, tc.b)
+		}
+	}
+}
+
+func TestParseRealMalformed(t *testing.T) {
+	for _, v := range [][]byte{
+		{0x80},
+		{0xC0},
+		{0x81, 0x01},
+		{0x82, 0x01, 0x02},
+		{0x83},
+		{0x83, 0x09},
+		{0x83, 0x02, 0x01},
+		{0x01},
+		{0x03},
+		{0x40, 0x00},
+	} {
+		if _, err := ParseReal(v); err == nil {
+			t.Errorf("% X: expected error", v)
+		}
+
+		// The same content must also surface as a decode error
+		data := append([]byte{byte(TagRealFloat), byte(len(v))}, v...)
+		if _, err := DecodePacketErr(data); err == nil {
+			t.Errorf("% X: expected decode error", data)
+		}
+	}
+}
+
+func TestNewReal(t *testing.T) {
+	for _, value := range []interface{}{
+		0.15625,
+		-1024.5,
+		float32(2.5),
+		math.Inf(1),
+		math.Inf(-1),
+		math.NaN(),
+		0.0,
+		negativeZero,
+	} {
+		p := NewReal(ClassUniversal, TypePrimitive, TagRealFloat, value, "real")
+		expected, ok := p.Value.(float64)
+		if !ok {
+			t.Errorf("%v: expected float64 Value, got %T", value, p.Value)
+			continue
+		}
+
+		decoded, err := DecodePacketErr(p.Bytes())
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", value, err)
+			continue
+		}
+		got, ok := decoded.Value.(float64)
+		if !ok {
+			t.Errorf("%v: expected decoded float64 Value, got %T", value, decoded.Value)
+			continue
+		}
+		if math.IsNaN(expected) {
+			if !math.IsNaN(got) {
+				t.Errorf("%v: expected NaN, got %v", value, got)
+			}
+		} else if got != expected || math.Signbit(got) != math.Signbit(expected) {
+			t.Errorf("%v: decoded as %v", value, got)
+		}
+	}
+}
+
+func TestDecodePacketReal(t *testing.T) {
+	for _, tc := range []struct {
+		data     []byte
+		expected float64
+	}{
+		{[]byte{0x09, 0x00}, 0.0},
+		{[]byte{0x09, 0x01, 0x40}, math.Inf(1)},
+		{[]byte{0x09, 0x01, 0x41}, math.Inf(-1)},
+		{[]byte{0x09, 0x01, 0x42}, math.NaN()},
+		{[]byte{0x09, 0x01, 0x43}, negativeZero},
+		{[]byte{0x09, 0x03, 0x80, 0xFB, 0x05}, 0.15625},
+		{NewReal(ClassUniversal, TypePrimitive, TagRealFloat, 3.25, "").Bytes(), 3.25},
+	} {
+		p := DecodePacket(tc.data)
+		if p == nil {
+			t.Errorf("% X: failed to decode", tc.data)
+			continue
+		}
+		v, ok := p.Value.(float64)
+		if !ok {
+			t.Errorf("% X: expected float64 Value, got %T", tc.data, p.Value)
+			continue
+		}
+		if math.IsNaN(tc.expected) {
+			if !math.IsNaN(v) {
+				t.Errorf("% X: expected NaN, got %v", tc.data, v)
+			}
+		} else if v != tc.expected || math.Signbit(v) != math.Signbit(tc.expected) {
+			t.Errorf("% X: expected %v, got %v", tc.data, tc.expected, v)
+		}
+	}
+}
+
+func TestParseRealZero(t *testing.T) {
+	v, err := ParseReal([]byte{})
+	if err != nil || v != 0 || math.Signbit(v) {
+		t.Errorf("empty content: expected +0, got %v (%v)", v, err)
+	}
+	v, err = ParseReal([]byte{0x43})
+	if err != nil || v != 0 || !math.Signbit(v) {
+		t.Errorf("special value: expected -0, got %v (%v)", v, err)
+	}
+
+	for _, malformed := range [][]byte{
+		{0x80, 0x00, 0x00},
+		{0xC0, 0x00, 0x00},
+		{0x80, 0x05, 0x00, 0x00},
+		{0x80, 0x05},
+	} {
+		if _, err := ParseReal(malformed); err == nil {
+			t.Errorf("% X: expected error for zero mantissa", malformed)
+		}
+	}
+}
+
+func TestParseRealLongExponentForm(t *testing.T) {
+	for _, tc := range []struct {
+		data     []byte
+		expected float64
+	}{
+		// exponent length given in the second octet
+		{[]byte{0xC3, 0x01, 0xFF, 0x03}, -1.5},
+		{[]byte{0xC3, 0x02, 0xFF, 0xFF, 0x03}, -1.5},
+		{[]byte{0xC3, 0x02, 0x00, 0x04, 0x03}, -48},
+		{[]byte{0x83, 0x02, 0x00, 0x04, 0x03}, 48},
+	} {
+		v, err := ParseReal(tc.data)
+		if err != nil {
+			t.Errorf("% X: unexpected error: %v", tc.data, err)
+		} else if v != tc.expected {
+			t.Errorf("% X: expected %v, got %v", tc.data, tc.expected, v)
+		}
+	}
+}
+
+func TestParseRealNegativeExponents(t *testing.T) {
+	// The exponent is a two's complement number for every exponent length
+	// encoded in the information octet (x.690, 8.5.7.4)
+	for _, tc := range []struct {
+		data     []byte
+		expected float64
+	}{
+		{[]byte{0x80, 0x80, 0x01}, 0x1p-128},
+		{[]byte{0xC0, 0x80, 0x01}, -0x1p-128},
+		{[]byte{0x81, 0xFF, 0x00, 0x01}, 0x1p-256},
+		{[]byte{0xC1, 0xFF, 0xF6, 0x03}, -0x3p-10},
+		{[]byte{0x82, 0xFF, 0xFC, 0x00, 0x01}, 0x1p-1024}, // subnormal
+		{[]byte{0xC2, 0xFF, 0xFF, 0xFE, 0x05}, -1.25},
+		{[]byte{0x82, 0x00, 0x00, 0x0A, 0x01}, 1024},
+	} {
+		v, err := ParseReal(tc.data)
+		if err != nil {
+			t.Errorf("% X: unexpected error: %v", tc.data, err)
+		} else if v != tc.expected {
+			t.Errorf("% X: expected %v, got %v", tc.data, tc.expected, v)
+		}
+	}
+}
+
+func TestParseRealVectors(t *testing.T) {
+	// Encodings following x.690, 8.5
+	for _, tc := range []struct {
+		data     []byte
+		expected float64
+	}{
+		{[]byte{0x80, 0x00, 0x01}, 1},             // 1 * 2^0
+		{[]byte{0x80, 0xFE, 0x01}, 0.25},          // 1 * 2^-2
+		{[]byte{0xC0, 0x02, 0x05}, -20},           // -5 * 2^2
+		{[]byte{0x90, 0x01, 0x01}, 8},             // 1 * 8^1
+		{[]byte{0xA0, 0xFF, 0x01}, 0.0625},        // 1 * 16^-1
+		{[]byte{0x84, 0x00, 0x01}, 2},             // 1 * 2^1 * 2^0, scaling factor 1
+		{[]byte{0x81, 0x01, 0x00, 0x01}, 0x1p256}, // two octet exponent
+		{[]byte{0x80, 0x00, 0x80}, 128},           // mantissa with the high bit set
+		{[]byte{0xC0, 0x00, 0xFF}, -255},          // N is unsigned, the sign is in bit 7
+		{[]byte{0x01, '1', '2'}, 12},              // NR1
+		{[]byte{0x02, '1', '.', '5'}, 1.5},        // NR2
+		{[]byte{0x03, '1', '5', 'E', '-', '1'}, 1.5},
+	} {
+		v, err := ParseReal(tc.data)
+		if err != nil {
+			t.Errorf("% X: unexpected error: %v", tc.data, err)
+		} else if v != tc.expected {
+			t.Errorf("% X: expected %v, got %v", tc.data, tc.expected, v)
+		}
+	}
+}
+
+func TestParseRealBase8(t *testing.T) {
+	for _, tc := range []struct {
+		data     []byte
+		expected float64
+	}{
+		{[]byte{0x90, 0x00, 0x05}, 5},              // 5 * 8^0
+		{[]byte{0x90, 0x02, 0x03}, 192},            // 3 * 8^2
+		{[]byte{0x94, 0xFF, 0x03}, 0.75},           // 3 * 2^1 * 8^-1, F scales by 2 for every base
+		{[]byte{0xD8, 0xFE, 0x07}, -0.4375},        // -7 * 2^2 * 8^-2
+		{[]byte{0x91, 0xFF, 0x00, 0x01}, 0x1p-768}, // 1 * 8^-256, two octet exponent
+		// a 63 bit mantissa scaled by 2^3 no longer fits an int64
+		{[]byte{0x9C, 0x01, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, 0x1p68},
+		{[]byte{0x92, 0x7F, 0xFF, 0xFF, 0x01}, math.Inf(1)},
+	} {
+		v, err := ParseReal(tc.data)
+		if err != nil {
+			t.Errorf("% X: unexpected error: %v", tc.data, err)
+		} else if v != tc.expected {
+			t.Errorf("% X: expected %v, got %v", tc.data, tc.expected, v)
+		}
+	}
+}
+
+func TestParseRealDecimal(t *testing.T) {
+	v, err := ParseReal([]byte{0x03, '3', '.', '1', '4', 'E', '0'})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != 3.14 {
+		t.Errorf("expected 3.14, got %v", v)
+	}
+
+	v, err = ParseReal([]byte{0x02, ' ', '3', ',', '1', '4'})
+	if err != nil || v != 3.14 {
+		t.Errorf("NR2 with comma: expected 3.14, got %v (%v)", v, err)
+	}
+
+	for _, invalid := range [][]byte{
+		{0x00, '1'},
+		{0x04, '1'},
+		{0x3F, '1'},
+		{0x01, '1', '.', '5'},
+	} {
+		if _, err := ParseReal(invalid); err == nil {
+			t.Errorf("% X: expected error", invalid)
+		}
+	}
+}
+
+// assertRealRoundTrip encodes v as REAL content and as a complete packet and
+// checks that both decode to exactly v. NaN only has to decode to a NaN, the
+// sign of zeros and infinities must be preserved, and finite values must be
+// bit for bit identical.
+func assertRealRoundTrip(t *testing.T, v float64) {
+	t.Helper()
+
+	check := func(path string, got float64, err error) {
+		t.Helper()
+		switch {
+		case err != nil:
+			t.Errorf("%v: unexpected error decoding %s: %v", v, path, err)
+		case math.IsNaN(v):
+			if !math.IsNaN(got) {
+				t.Errorf("NaN: %s decoded as %v", path, got)
+			}
+		case math.Float64bits(got) != math.Float64bits(v):
+			t.Errorf("%v (%016X): %s decoded as %v (%016X)", v, math.Float64bits(v), path, got, math.Float64bits(got))
+		}
+	}
+
+	got, err := ParseReal(encodeFloat(v))
+	check("content", got, err)
+
+	p, err := DecodePacketErr(NewReal(ClassUniversal, TypePrimitive, TagRealFloat, v, "").Bytes())
+	if err != nil {
+		check("packet", 0, err)
+		return
+	}
+	got, _ = p.Value.(float64)
+	check("packet", got, nil)
+}
+
+func TestRealRoundTrip(t *testing.T) {
+	for _, v := range []float64{
+		0,
+		negativeZero,
+		math.Inf(1),
+		math.Inf(-1),
+		math.NaN(),
+		1,
+		-1,
+		0.1,
+		-0.1,
+		100,
+		1.0 / 3,
+		math.Pi,
+		-math.E,
+		123456789.123,
+		1e21,
+		1e-7,
+		1e300,
+		-1e-300,
+		1 << 53,
+		1<<53 + 2,
+		math.MaxFloat64,
+		-math.MaxFloat64,
+		math.SmallestNonzeroFloat64,
+		-math.SmallestNonzeroFloat64,
+		0x1p-1022,
+		math.MaxFloat32,
+		float64(float32(0.1)),
+	} {
+		assertRealRoundTrip(t, v)
+	}
+}
+
+func TestRealCanonicalNaN(t *testing.T) {
+	for _, bits := range []uint64{
+		0x7FF8000000000001, // math.NaN()
+		0x7FF8000000000000, // quiet NaN
+		0xFFF8000000000000, // negative quiet NaN
+		0x7FF0000000000001, // signaling NaN
+		0x7FF4000000000000, // signaling NaN with another payload
+		0xFFFFFFFFFFFFFFFF, // all bits set
+	} {
+		v := math.Float64frombits(bits)
+		if !math.IsNaN(v) {
+			t.Fatalf("%016X is not a NaN", bits)
+		}
+		for _, value := range []interface{}{v, float32(v)} {
+			p := NewReal(ClassUniversal, TypePrimitive, TagRealFloat, value, "")
+			if !bytes.Equal([]byte{0x09, 0x01, 0x42}, p.Bytes()) {
+				t.Errorf("%016X (%T): expected canonical NaN encoding, got % X", bits, value, p.Bytes())
+			}
+		}
+	}
+
+	decoded, err := ParseReal([]byte{0x42})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bits := math.Float64bits(decoded); !math.IsNaN(decoded) || bits&(1<<51) == 0 {
+		t.Errorf("expected a quiet NaN, got %016X", bits)
+	}
+}
+
+func TestEncodeFloatDER(t *testing.T) {
+	for _, v := range []float64{
+		1, -1, 0.5, 3, 255, -1e300, math.MaxFloat64, math.SmallestNonzeroFloat64, 0.1, math.Pi,
+	} {
+		encoded := encodeFloatDER(v)
+		decoded, err := ParseReal(encoded)
+		if err != nil {
+			t.Errorf("%v: unexpected error decoding % X: %v", v, encoded, err)
+			continue
+		}
+		if decoded != v {
+			t.Errorf("%v: decoded % X as %v", v, encoded, decoded)
+		}
+		if encoded[len(encoded)-1]&1 == 0 {
+			t.Errorf("%v: expected an odd mantissa, got % X", v, encoded)
+		}
+	}
+
+	// mantissas with the high bit set need no leading zero octet
+	for _, tc := range []struct {
+		v        float64
+		expected []byte
+	}{
+		{255, []byte{0x80, 0x00, 0xFF}},
+		{-255, []byte{0xC0, 0x00, 0xFF}},
+		{129, []byte{0x80, 0x00, 0x81}},
+		{128, []byte{0x80, 0x07, 0x01}},
+	} {
+		if encoded := encodeFloatDER(tc.v); !bytes.Equal(tc.expected, encoded) {
+			t.Errorf("%v: expected % X, got % X", tc.v, tc.expected, encoded)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package ber
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Framing bytes of the Ember+ S101 protocol
+const (
+	s101BOF     = 0xFE // begin of frame
+	s101EOF     = 0xFF // end of frame
+	s101CE      = 0xFD // escape character
+	s101XOR     = 0x20 // escaped bytes are XORed with this value
+	s101Invalid = 0xF8 // bytes from this value on must be escaped
+
+	s101MessageEmBER = 0x0E
+	s101CommandEmBER = 0x00
+	s101Version      = 0x01
+	s101FlagFirst    = 0x80
+	s101FlagLast     = 0x40
+	s101DTDGlow      = 0x01
+)
+
+// s101GlowVersion holds the application bytes announcing Glow DTD version 2.40
+var s101GlowVersion = []byte{0x28, 0x02}
+
+// Encoder writes encoded packets to an io.Writer.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns an Encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the BER encoding of p.
+func (e *Encoder) Encode(p *Packet) error {
+	_, err := e.w.Write(p.Bytes())
+	return err
+}
+
+// EncodeFramed writes the BER encoding of p as a single Ember+ S101 frame,
+// i.e. with the EmBER message header, escaping and CRC, as sent to an Ember+
+// provider or consumer.
+func (e *Encoder) EncodeFramed(p *Packet) error {
+	_, err := e.w.Write(encodeS101Frame(p.Bytes()))
+	return err
+}
+
+func encodeS101Frame(payload []byte) []byte {
+	message := []byte{0x00, s101MessageEmBER, s101CommandEmBER, s101Version, s101FlagFirst | s101FlagLast, s101DTDGlow, byte(len(s101GlowVersion))}
+	message = append(message, s101GlowVersion...)
+	message = append(message, payload...)
+	return s101Frame(message)
+}
+
+// s101Frame appends the CRC to message and escapes it into a frame.
+func s101Frame(message []byte) []byte {
+	crc := ^s101CRC(0xFFFF, message)
+	message = append(message[:len(message):len(message)], byte(crc), byte(crc>>8))
+
+	frame := make([]byte, 0, len(message)+len(message)/16+2)
+	frame = append(frame, s101BOF)
+	for _, b := range message {
+		if b >= s101Invalid {
+			frame = append(frame, s101CE, b^s101XOR)
+			continue
+		}
+		frame = append(frame, b)
+	}
+	return append(frame, s101EOF)
+}
+
+// s101CRC updates crc with data using the reflected CRC-CCITT polynomial
+// (CRC-16/X-25) used by S101.
+func s101CRC(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// S101Reader reads the BER payload of Ember+ S101 frames.
+type S101Reader struct {
+	r *bufio.Reader
+}
+
+// NewS101Reader returns an S101Reader reading frames from r.
+func NewS101Reader(r io.Reader) *S101Reader {
+	return &S101Reader{r: bufio.NewReader(r)}
+}
+
+// ReadPayload reads frames up to the last frame of the next EmBER message and
+// returns its concatenated BER payload. Frames of other messages, e.g. keep
+// alive requests, are skipped.
+func (s *S101Reader) ReadPayload() ([]byte, error) {
+	var payload []byte
+	for {
+		message, err := s.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if len(message) < 4 || message[1] != s101MessageEmBER || message[2] != s101CommandEmBER {
+			continue
+		}
+		if len(message) < 7 || len(message) < 7+int(message[6]) {
+			return nil, errors.New("truncated S101 EmBER header")
+		}
+		flags := message[4]
+		payload = append(payload, message[7+int(message[6]):]...)
+		if flags&s101FlagLast != 0 {
+			return payload, nil
+		}
+	}
+}
+
+// readFrame returns the unescaped content of the next frame, without its CRC.
+func (s *S101Reader) readFrame() ([]byte, error) {
+	// Skip anything up to the start of the next frame
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == s101BOF {
+			break
+		}
+	}
+
+	var message []byte
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		switch b {
+		case s101EOF:
+			if len(message) < 2 {
+				return nil, errors.New("S101 frame too short")
+			}
+			// The CRC over the content and the transmitted CRC yields the X.25 residue
+			if crc := s101CRC(0xFFFF, message); crc != 0xF0B8 {
+				return nil, fmt.Errorf("invalid S101 frame CRC, residue %04X", crc)
+			}
+			return message[:len(message)-2], nil
+		case s101BOF:
+			return nil, errors.New("unexpected begin of S101 frame")
+		case s101CE:
+			if b, err = s.r.ReadByte(); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			b ^= s101XOR
+		}
+		message = append(message, b)
+	}
+}
@@ -0,0 +1,64 @@
+package ber
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestS101CRC(t *testing.T) {
+	// Check value of CRC-16/X-25
+	if crc := ^s101CRC(0xFFFF, []byte("123456789")); crc != 0x906E {
+		t.Errorf("expected CRC 906E, got %04X", crc)
+	}
+}
+
+func TestEncodeFramed(t *testing.T) {
+	// GetDirectory command for a parameter holding bytes that need escaping
+	cmd := Encode(ClassApplication, TypeConstructed, TagGlowCommand, nil, "Command")
+	number := Encode(ClassContext, TypeConstructed, 0, nil, "number")
+	number.AppendChild(Integer(int64(CommandGetDirectory)))
+	cmd.AppendChild(number)
+	root := Encode(ClassApplication, TypeConstructed, 0, nil, "Root")
+	root.AppendChild(cmd)
+	root.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "\xfe\xff\xfd\xf8", "escaped"))
+
+	var buf bytes.Buffer
+	// a keep-alive request in front of the EmBER frame is skipped
+	buf.Write(s101Frame([]byte{0x00, s101MessageEmBER, 0x01, s101Version}))
+	if err := NewEncoder(&buf).EncodeFramed(root); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	frame := buf.Bytes()
+	if n := bytes.Count(frame, []byte{s101EOF}); n != 2 {
+		t.Errorf("expected only the two frame ends to hold 0xFF, found %d", n)
+	}
+
+	r := NewS101Reader(bytes.NewReader(frame))
+	payload, err := r.ReadPayload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := DecodePacketErr(payload)
+	if err != nil {
+		t.Fatalf("unexpected error decoding payload: %v", err)
+	}
+	if !p.Equal(root) {
+		t.Errorf("expected % X, got % X", root.Bytes(), p.Bytes())
+	}
+	if c, err := DecodeGlowCommand(p.Children[0]); err != nil || c != CommandGetDirectory {
+		t.Errorf("expected GetDirectory, got %s (%v)", c, err)
+	}
+	if _, err := r.ReadPayload(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	corrupted := append([]byte{}, frame...)
+	corrupted[len(corrupted)-5] ^= 0x01
+	if _, err := NewS101Reader(bytes.NewReader(corrupted)).ReadPayload(); err == nil {
+		t.Error("expected CRC error for a corrupted frame")
+	}
+	if _, err := NewS101Reader(bytes.NewReader(frame[:len(frame)-1])).ReadPayload(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF for a truncated frame, got %v", err)
+	}
+}
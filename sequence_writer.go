@@ -0,0 +1,61 @@
+package ber
+
+import (
+	"errors"
+	"io"
+)
+
+// SequenceWriter streams the elements of a SEQUENCE OF to a writer. The
+// sequence is encoded with indefinite length, so neither the number of
+// elements nor their total size need to be known in advance.
+type SequenceWriter struct {
+	out     io.Writer
+	started bool
+	closed  bool
+}
+
+// NewSequenceWriter returns a SequenceWriter writing a universal SEQUENCE to out.
+func NewSequenceWriter(out io.Writer) *SequenceWriter {
+	return &SequenceWriter{out: out}
+}
+
+// Add encodes p and writes it as the next element of the sequence.
+func (w *SequenceWriter) Add(p *Packet) error {
+	if w.closed {
+		return errors.New("sequence writer is closed")
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	_, err := w.out.Write(p.Bytes())
+	return err
+}
+
+// Close terminates the sequence with an end-of-contents marker. It does not
+// close the underlying writer.
+func (w *SequenceWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.closed = true
+	_, err := w.out.Write([]byte{byte(TagEOC), 0x00})
+	return err
+}
+
+func (w *SequenceWriter) writeHeader() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	header := encodeIdentifier(Identifier{
+		ClassType: ClassUniversal,
+		TagType:   TypeConstructed,
+		Tag:       TagSequence,
+	})
+	header = append(header, LengthLongFormBitmask)
+	_, err := w.out.Write(header)
+	return err
+}
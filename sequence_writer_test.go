@@ -0,0 +1,41 @@
+package ber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSequenceWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSequenceWriter(&buf)
+
+	for i := 0; i < 100; i++ {
+		if err := w.Add(NewInteger(ClassUniversal, TypePrimitive, TagInteger, int64(i), "")); err != nil {
+			t.Fatalf("error adding element %d: %v", i, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("error closing sequence writer: %v", err)
+	}
+
+	if !bytes.HasPrefix(buf.Bytes(), []byte{0x30, 0x80}) {
+		t.Errorf("expected indefinite-length sequence header, got % X", buf.Bytes()[:2])
+	}
+
+	p, err := DecodePacketErr(buf.Bytes())
+	if err != nil {
+		t.Fatalf("error decoding streamed sequence: %v", err)
+	}
+	if len(p.Children) != 100 {
+		t.Fatalf("expected 100 children, got %d", len(p.Children))
+	}
+	for i, child := range p.Children {
+		if v, ok := child.Value.(int64); !ok || v != int64(i) {
+			t.Errorf("child %d: expected %d, got %v", i, i, child.Value)
+		}
+	}
+
+	if err := w.Add(NewInteger(ClassUniversal, TypePrimitive, TagInteger, 0, "")); err == nil {
+		t.Error("expected error adding to a closed sequence writer")
+	}
+}
@@ -0,0 +1,29 @@
+package ber
+
+// Shorthand constructors for the common case of universal, primitive values
+// with their standard tag and no description.
+
+// String returns a universal OCTET STRING packet holding v.
+func String(v string) *Packet {
+	return NewString(ClassUniversal, TypePrimitive, TagOctetString, v, "")
+}
+
+// Integer returns a universal INTEGER packet holding v.
+func Integer(v int64) *Packet {
+	return NewInteger(ClassUniversal, TypePrimitive, TagInteger, v, "")
+}
+
+// Bool returns a universal BOOLEAN packet holding v.
+func Bool(v bool) *Packet {
+	return NewBoolean(ClassUniversal, TypePrimitive, TagBoolean, v, "")
+}
+
+// Enumerated returns a universal ENUMERATED packet holding v.
+func Enumerated(v int64) *Packet {
+	return NewEnumerated(ClassUniversal, TypePrimitive, TagEnumerated, v, "")
+}
+
+// Null returns a universal NULL packet.
+func Null() *Packet {
+	return Encode(ClassUniversal, TypePrimitive, TagNULL, nil, "")
+}
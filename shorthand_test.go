@@ -0,0 +1,25 @@
+package ber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestShorthandConstructors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		short, verbose *Packet
+	}{
+		"String":     {String("value"), NewString(ClassUniversal, TypePrimitive, TagOctetString, "value", "String")},
+		"Integer":    {Integer(-129), NewInteger(ClassUniversal, TypePrimitive, TagInteger, int64(-129), "Integer")},
+		"Bool":       {Bool(true), NewBoolean(ClassUniversal, TypePrimitive, TagBoolean, true, "Boolean")},
+		"Enumerated": {Enumerated(3), NewEnumerated(ClassUniversal, TypePrimitive, TagEnumerated, 3, "Enumerated")},
+		"Null":       {Null(), Encode(ClassUniversal, TypePrimitive, TagNULL, nil, "NULL")},
+	} {
+		if !bytes.Equal(tc.verbose.Bytes(), tc.short.Bytes()) {
+			t.Errorf("%s: expected % X, got % X", name, tc.verbose.Bytes(), tc.short.Bytes())
+		}
+		if tc.short.Value != tc.verbose.Value {
+			t.Errorf("%s: expected value %v, got %v", name, tc.verbose.Value, tc.short.Value)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package ber
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// SNMP message versions
+const (
+	SNMPVersion3 = 3
+)
+
+// Flags of the msgFlags field of an SNMPv3 message (RFC 3412)
+const (
+	SNMPv3FlagAuth       byte = 0x01
+	SNMPv3FlagPriv       byte = 0x02
+	SNMPv3FlagReportable byte = 0x04
+)
+
+// Application-class tags of the SMI (RFC 2578)
+const (
+	TagSMIIPAddress Tag = 0
+)
+
+// SNMPv3SecurityModelUSM is the msgSecurityModel of the User-based Security Model (RFC 3414)
+const SNMPv3SecurityModelUSM = 3
+
+// SNMPv3Message holds the fields of a decoded SNMPv3 message (RFC 3412).
+type SNMPv3Message struct {
+	MsgID         int64
+	MaxSize       int64
+	Flags         byte
+	SecurityModel int64
+	// SecurityParameters holds the raw content of msgSecurityParameters
+	SecurityParameters []byte
+	// USM is set when the User-based Security Model is used
+	USM *USMSecurityParameters
+	// Data is the msgData, either a plaintext ScopedPDU or an encrypted OCTET STRING
+	Data *Packet
+}
+
+// USMSecurityParameters holds the fields of UsmSecurityParameters (RFC 3414).
+type USMSecurityParameters struct {
+	AuthoritativeEngineID    []byte
+	AuthoritativeEngineBoots int64
+	AuthoritativeEngineTime  int64
+	UserName                 string
+	AuthenticationParameters []byte
+	PrivacyParameters        []byte
+}
+
+// DecodeSNMPv3Message extracts the header fields of a decoded SNMPv3 message:
+//
+//	SNMPv3Message ::= SEQUENCE {
+//	    msgVersion            INTEGER,
+//	    msgGlobalData         HeaderData,
+//	    msgSecurityParameters OCTET STRING,
+//	    msgData               ScopedPduData }
+func DecodeSNMPv3Message(p *Packet) (*SNMPv3Message, error) {
+	if err := expectSequence(p, "SNMPv3 message", 4); err != nil {
+		return nil, err
+	}
+
+	version, err := integerChild(p, 0, "msgVersion")
+	if err != nil {
+		return nil, err
+	}
+	if version != SNMPVersion3 {
+		return nil, fmt.Errorf("unsupported SNMP message version %d", version)
+	}
+
+	header := p.Children[1]
+	if err := expectSequence(header, "msgGlobalData", 4); err != nil {
+		return nil, err
+	}
+	msg := &SNMPv3Message{}
+	if msg.MsgID, err = integerChild(header, 0, "msgID"); err != nil {
+		return nil, err
+	}
+	if msg.MaxSize, err = integerChild(header, 1, "msgMaxSize"); err != nil {
+		return nil, err
+	}
+	flags, err := octetStringChild(header, 2, "msgFlags")
+	if err != nil {
+		return nil, err
+	}
+	if len(flags) != 1 {
+		return nil, fmt.Errorf("msgFlags must be a single octet, got %d", len(flags))
+	}
+	msg.Flags = flags[0]
+	if msg.SecurityModel, err = integerChild(header, 3, "msgSecurityModel"); err != nil {
+		return nil, err
+	}
+
+	if msg.SecurityParameters, err = octetStringChild(p, 2, "msgSecurityParameters"); err != nil {
+		return nil, err
+	}
+	if msg.SecurityModel == SNMPv3SecurityModelUSM {
+		if msg.USM, err = decodeUSMSecurityParameters(msg.SecurityParameters); err != nil {
+			return nil, err
+		}
+	}
+
+	msg.Data = p.Children[3]
+	return msg, nil
+}
+
+func decodeUSMSecurityParameters(data []byte) (*USMSecurityParameters, error) {
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid USM security parameters: %w", err)
+	}
+	if err := expectSequence(p, "USM security parameters", 6); err != nil {
+		return nil, err
+	}
+
+	usm := &USMSecurityParameters{}
+	if usm.AuthoritativeEngineID, err = octetStringChild(p, 0, "msgAuthoritativeEngineID"); err != nil {
+		return nil, err
+	}
+	if usm.AuthoritativeEngineBoots, err = integerChild(p, 1, "msgAuthoritativeEngineBoots"); err != nil {
+		return nil, err
+	}
+	if usm.AuthoritativeEngineTime, err = integerChild(p, 2, "msgAuthoritativeEngineTime"); err != nil {
+		return nil, err
+	}
+	userName, err := octetStringChild(p, 3, "msgUserName")
+	if err != nil {
+		return nil, err
+	}
+	usm.UserName = string(userName)
+	if usm.AuthenticationParameters, err = octetStringChild(p, 4, "msgAuthenticationParameters"); err != nil {
+		return nil, err
+	}
+	if usm.PrivacyParameters, err = octetStringChild(p, 5, "msgPrivacyParameters"); err != nil {
+		return nil, err
+	}
+	return usm, nil
+}
+
+func expectSequence(p *Packet, name string, children int) error {
+	if p == nil {
+		return errors.New("nil packet")
+	}
+	if p.ClassType != ClassUniversal || p.TagType != TypeConstructed || p.Tag != TagSequence {
+		return fmt.Errorf("%s must be a SEQUENCE", name)
+	}
+	if len(p.Children) != children {
+		return fmt.Errorf("%s must have %d elements, got %d", name, children, len(p.Children))
+	}
+	return nil
+}
+
+func integerChild(p *Packet, index int, name string) (int64, error) {
+	child := p.Children[index]
+	v, ok := child.Value.(int64)
+	if child.ClassType != ClassUniversal || child.Tag != TagInteger || !ok {
+		return 0, fmt.Errorf("%s must be an INTEGER", name)
+	}
+	return v, nil
+}
+
+func octetStringChild(p *Packet, index int, name string) ([]byte, error) {
+	child := p.Children[index]
+	if child.ClassType != ClassUniversal || child.TagType != TypePrimitive || child.Tag != TagOctetString {
+		return nil, fmt.Errorf("%s must be an OCTET STRING", name)
+	}
+	return child.Data.Bytes(), nil
+}
+
+// VarBind is a variable binding of an SNMP PDU.
+type VarBind struct {
+	OID   OID
+	Value *Packet
+}
+
+// NewVarBindList returns the SEQUENCE OF SEQUENCE { name OBJECT IDENTIFIER,
+// value } holding binds, as used in every SNMP PDU. A nil Value is encoded as
+// NULL, as is customary in requests.
+func NewVarBindList(binds []VarBind) *Packet {
+	list := NewSequence("VarBindList")
+	for _, bind := range binds {
+		varBind := NewSequence("VarBind")
+		varBind.AppendChild(NewOID(ClassUniversal, TypePrimitive, TagObjectIdentifier, bind.OID.String(), "name"))
+		value := bind.Value
+		if value == nil {
+			value = Encode(ClassUniversal, TypePrimitive, TagNULL, nil, "value")
+		}
+		varBind.AppendChild(value)
+		list.AppendChild(varBind)
+	}
+	return list
+}
+
+// DecodeSMIIPAddress decodes the content of an SMI IpAddress, a 4 octet
+// IPv4 address in network byte order, into a net.IP. Set it in
+// DecodeOptions.ApplicationDecoders for TagSMIIPAddress to have IpAddress
+// values decoded.
+func DecodeSMIIPAddress(content []byte) (interface{}, error) {
+	if len(content) != net.IPv4len {
+		return nil, fmt.Errorf("IpAddress must be %d octets, got %d", net.IPv4len, len(content))
+	}
+	return net.IPv4(content[0], content[1], content[2], content[3]), nil
+}
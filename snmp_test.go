@@ -0,0 +1,128 @@
+package ber
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDecodeSNMPv3Message(t *testing.T) {
+	// Engine ID discovery request, reportable and unauthenticated, using USM
+	data := []byte{
+		0x30, 0x3e,
+		0x02, 0x01, 0x03,
+		0x30, 0x11, 0x02, 0x04, 0x12, 0x34, 0x56, 0x78, 0x02, 0x03, 0x00, 0xff, 0xe3, 0x04, 0x01, 0x04, 0x02, 0x01, 0x03,
+		0x04, 0x10, 0x30, 0x0e, 0x04, 0x00, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00, 0x04, 0x00,
+		0x30, 0x14, 0x04, 0x00, 0x04, 0x00,
+		0xa0, 0x0e, 0x02, 0x04, 0x1f, 0x2e, 0x3d, 0x4c, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00, 0x30, 0x00,
+	}
+
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error decoding packet: %v", err)
+	}
+	msg, err := DecodeSNMPv3Message(p)
+	if err != nil {
+		t.Fatalf("unexpected error decoding message: %v", err)
+	}
+
+	if msg.MsgID != 0x12345678 {
+		t.Errorf("expected msgID %d, got %d", 0x12345678, msg.MsgID)
+	}
+	if msg.MaxSize != 65507 {
+		t.Errorf("expected msgMaxSize 65507, got %d", msg.MaxSize)
+	}
+	if msg.Flags != SNMPv3FlagReportable {
+		t.Errorf("expected msgFlags %02X, got %02X", SNMPv3FlagReportable, msg.Flags)
+	}
+	if msg.SecurityModel != SNMPv3SecurityModelUSM || msg.USM == nil {
+		t.Fatalf("expected USM security parameters, got model %d", msg.SecurityModel)
+	}
+	if len(msg.USM.AuthoritativeEngineID) != 0 || msg.USM.UserName != "" {
+		t.Errorf("expected empty engine ID and user name for discovery, got %v", msg.USM)
+	}
+	if msg.Data == nil || len(msg.Data.Children) != 3 {
+		t.Errorf("expected scoped PDU as msgData")
+	}
+
+	p.Children[0] = NewInteger(ClassUniversal, TypePrimitive, TagInteger, 1, "")
+	if _, err := DecodeSNMPv3Message(p); err == nil {
+		t.Error("expected error for SNMPv2c version")
+	}
+}
+
+func TestNewVarBindList(t *testing.T) {
+	list := NewVarBindList([]VarBind{
+		{OID: OID{1, 3, 6, 1, 2, 1, 1, 5, 0}, Value: NewString(ClassUniversal, TypePrimitive, TagOctetString, "router1", "sysName")},
+		{OID: OID{1, 3, 6, 1, 2, 1, 1, 3, 0}},
+	})
+
+	decoded, err := DecodePacketErr(list.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decoded.Children) != 2 {
+		t.Fatalf("expected 2 bindings, got %d", len(decoded.Children))
+	}
+
+	for i, tc := range []struct {
+		oid   string
+		tag   Tag
+		value interface{}
+	}{
+		{"1.3.6.1.2.1.1.5.0", TagOctetString, "router1"},
+		{"1.3.6.1.2.1.1.3.0", TagNULL, nil},
+	} {
+		bind := decoded.Children[i]
+		if len(bind.Children) != 2 {
+			t.Errorf("binding %d: expected 2 elements, got %d", i, len(bind.Children))
+			continue
+		}
+		if bind.Children[0].Value != tc.oid {
+			t.Errorf("binding %d: expected name %s, got %v", i, tc.oid, bind.Children[0].Value)
+		}
+		if bind.Children[1].Tag != tc.tag || bind.Children[1].Value != tc.value {
+			t.Errorf("binding %d: expected value %v with tag %d, got %v with tag %d", i, tc.value, tc.tag, bind.Children[1].Value, bind.Children[1].Tag)
+		}
+	}
+}
+
+func TestDecodeSMIIPAddress(t *testing.T) {
+	list := NewVarBindList([]VarBind{{
+		OID:   OID{1, 3, 6, 1, 2, 1, 4, 20, 1, 1, 192, 0, 2, 1},
+		Value: NewString(ClassApplication, TypePrimitive, TagSMIIPAddress, "\xc0\x00\x02\x01", "ipAdEntAddr"),
+	}})
+	data := list.Bytes()
+
+	p, err := DecodePacketErr(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := p.Children[0].Children[1].Value; v != nil {
+		t.Errorf("expected no value without a decoder, got %v", v)
+	}
+
+	opts := DecodeOptions{ApplicationDecoders: map[Tag]ApplicationDecoder{TagSMIIPAddress: DecodeSMIIPAddress}}
+	p, err = DecodePacketOptions(data, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ip, ok := p.Children[0].Children[1].Value.(net.IP)
+	if !ok || !ip.Equal(net.IPv4(192, 0, 2, 1)) {
+		t.Errorf("expected 192.0.2.1, got %v (%T)", p.Children[0].Children[1].Value, p.Children[0].Children[1].Value)
+	}
+
+	// a constructed [APPLICATION 0], e.g. an LDAP BindRequest, is left alone
+	bind := Encode(ClassApplication, TypeConstructed, 0, nil, "BindRequest")
+	bind.AppendChild(Integer(3))
+	if _, err := DecodePacketOptions(bind.Bytes(), opts); err != nil {
+		t.Errorf("unexpected error for a constructed packet: %v", err)
+	}
+
+	// an [APPLICATION 0] of another protocol is not decoded as an IpAddress
+	if _, err := DecodePacketErr([]byte{0x40, 0x03, 0xc0, 0x00, 0x02}); err != nil {
+		t.Errorf("unexpected error without decoders: %v", err)
+	}
+	if _, err := DecodePacketOptions([]byte{0x40, 0x03, 0xc0, 0x00, 0x02}, opts); err == nil {
+		t.Error("expected error for a 3 octet IpAddress")
+	}
+}
@@ -0,0 +1,57 @@
+package ber
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseUTCTime parses the content of a UTCTime, i.e. YYMMDDhhmm[ss] followed
+// by either Z or a +hhmm / -hhmm offset (x.680, 47.3). Two digit years are
+// mapped as in RFC 5280, 4.1.2.5.1: 00 to 49 are 2000 to 2049, 50 to 99 are
+// 1950 to 1999.
+func ParseUTCTime(v []byte) (time.Time, error) {
+	str := DecodeString(v)
+
+	var format string
+	tzIndex := strings.IndexAny(str, "Z+-")
+	switch tzIndex {
+	case 10:
+		format = `0601021504`
+	case 12:
+		format = `060102150405`
+	default:
+		return zeroTime, ErrInvalidTimeFormat
+	}
+	switch len(str) - tzIndex {
+	case 1, 5:
+		format += `Z0700`
+	default:
+		return zeroTime, ErrInvalidTimeFormat
+	}
+
+	t, err := time.Parse(format, str)
+	if err != nil {
+		return zeroTime, ErrInvalidTimeFormat
+	}
+	// time.Parse maps 69 to 99 to the 20th century, RFC 5280 already from 50
+	if t.Year() >= 2050 {
+		t = t.AddDate(-100, 0, 0)
+	}
+	return t, nil
+}
+
+// NewUTCTime returns a UTCTime packet holding value in UTC, with seconds. It
+// panics if the year of value is outside of 1950 to 2049, which can't be
+// represented by the two digit year of UTCTime.
+func NewUTCTime(classType Class, tagType Type, tag Tag, value time.Time, description string) *Packet {
+	value = value.UTC()
+	if year := value.Year(); year < 1950 || year > 2049 {
+		panic(fmt.Sprintf("ber: year %d can't be encoded as UTCTime", year))
+	}
+	p := Encode(classType, tagType, tag, nil, description)
+	s := value.Format(`060102150405Z`)
+	p.Value = s
+	p.Data.Write([]byte(s))
+	return p
+}
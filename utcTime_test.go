@@ -0,0 +1,87 @@
+package ber
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseUTCTime(t *testing.T) {
+	for _, tc := range []struct {
+		in     string
+		wanted time.Time
+	}{
+		{"490101000000Z", time.Date(2049, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"500101000000Z", time.Date(1950, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"991231235959Z", time.Date(1999, time.December, 31, 23, 59, 59, 0, time.UTC)},
+		{"000101000000Z", time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"680229120000Z", time.Date(1968, time.February, 29, 12, 0, 0, 0, time.UTC)},
+		{"2312251330Z", time.Date(2023, time.December, 25, 13, 30, 0, 0, time.UTC)},
+		{"231225143000+0100", time.Date(2023, time.December, 25, 13, 30, 0, 0, time.UTC)},
+		{"2312250830-0500", time.Date(2023, time.December, 25, 13, 30, 0, 0, time.UTC)},
+	} {
+		got, err := ParseUTCTime([]byte(tc.in))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.in, err)
+		} else if !got.Equal(tc.wanted) {
+			t.Errorf("%s: expected %s, got %s", tc.in, tc.wanted, got)
+		}
+	}
+
+	for _, invalid := range []string{"", "2312251330", "23122513Z", "231225133000.5Z", "231225133000+01", "231325133000Z"} {
+		if _, err := ParseUTCTime([]byte(invalid)); err == nil {
+			t.Errorf("%q: expected error", invalid)
+		}
+	}
+
+	p, err := DecodePacketErr([]byte{0x17, 0x0d, '4', '9', '0', '1', '0', '1', '0', '0', '0', '0', '0', '0', 'Z'})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, ok := p.Value.(time.Time); !ok || v.Year() != 2049 {
+		t.Errorf("expected time.Time in 2049, got %v", p.Value)
+	}
+}
+
+func TestNewUTCTime(t *testing.T) {
+	p := NewUTCTime(ClassUniversal, TypePrimitive, TagUTCTime, time.Date(2023, time.December, 25, 14, 30, 0, 0, time.FixedZone("", 3600)), "")
+	if got := string(p.Data.Bytes()); got != "231225133000Z" {
+		t.Errorf("expected 231225133000Z, got %s", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for year 2050")
+		}
+	}()
+	NewUTCTime(ClassUniversal, TypePrimitive, TagUTCTime, time.Date(2050, time.January, 1, 0, 0, 0, 0, time.UTC), "")
+}
+
+func TestTimeConstructorsNormalizeToUTC(t *testing.T) {
+	berlin := time.FixedZone("CET", 3600)
+	value := time.Date(2024, time.March, 1, 0, 15, 30, 0, berlin)
+
+	for _, tc := range []struct {
+		p     *Packet
+		want  string
+		parse func([]byte) (time.Time, error)
+	}{
+		{NewUTCTime(ClassUniversal, TypePrimitive, TagUTCTime, value, ""), "240229231530Z", ParseUTCTime},
+		{NewGeneralizedTime(ClassUniversal, TypePrimitive, TagGeneralizedTime, value, ""), "20240229231530Z", ParseGeneralizedTime},
+	} {
+		if got := string(tc.p.Data.Bytes()); got != tc.want {
+			t.Errorf("expected %s, got %s", tc.want, got)
+		}
+		decoded, err := tc.parse(tc.p.Data.Bytes())
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.want, err)
+		} else if !decoded.Equal(value) || decoded.Location() != time.UTC {
+			t.Errorf("%s: expected %s in UTC, got %s", tc.want, value, decoded)
+		}
+	}
+
+	// UTCTime has no fractional seconds, they are truncated
+	p := NewUTCTime(ClassUniversal, TypePrimitive, TagUTCTime, value.Add(500*time.Millisecond), "")
+	if got := string(p.Data.Bytes()); got != "240229231530Z" {
+		t.Errorf("expected fractional seconds to be dropped, got %s", got)
+	}
+}
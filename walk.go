@@ -0,0 +1,75 @@
+package ber
+
+// Walk calls fn for p and all of its descendants in pre-order, passing the
+// nesting depth of the visited packet, 0 for p itself. Returning false from fn
+// skips the children of the visited packet.
+func (p *Packet) Walk(fn func(depth int, p *Packet) bool) {
+	p.walk(make([]int, 0, 8), func(path []int, p *Packet) bool {
+		return fn(len(path), p)
+	})
+}
+
+// walk is like Walk, but passes the child indices leading from the root to
+// the visited packet. path is reused between calls.
+func (p *Packet) walk(path []int, fn func(path []int, p *Packet) bool) {
+	if !fn(path, p) {
+		return
+	}
+	for i, child := range p.Children {
+		child.walk(append(path, i), fn)
+	}
+}
+
+// FindChildrenByTag returns all descendants of p with the given tag, of any
+// class, in pre-order. Tag numbers only have a fixed meaning within a class,
+// e.g. [APPLICATION 2] is unrelated to a universal INTEGER, see
+// FindChildrenByClassAndTag.
+func (p *Packet) FindChildrenByTag(tag Tag) []*Packet {
+	var found []*Packet
+	p.Walk(func(depth int, child *Packet) bool {
+		if depth > 0 && child.Tag == tag {
+			found = append(found, child)
+		}
+		return true
+	})
+	return found
+}
+
+// FindChildrenByClassAndTag returns all descendants of p with the given class
+// and tag in pre-order.
+func (p *Packet) FindChildrenByClassAndTag(class Class, tag Tag) []*Packet {
+	var found []*Packet
+	p.Walk(func(depth int, child *Packet) bool {
+		if depth > 0 && child.ClassType == class && child.Tag == tag {
+			found = append(found, child)
+		}
+		return true
+	})
+	return found
+}
+
+// Record is a single packet of a flattened tree, see Flatten.
+type Record struct {
+	// Path holds the child indices leading from the root to the packet
+	Path  []int
+	Tag   Tag
+	Class Class
+	// Value is the decoded value of the packet, nil for constructed packets
+	Value interface{}
+}
+
+// Flatten returns a Record for p and each of its descendants in depth-first
+// order, e.g. for exporting a decoded tree to CSV or JSON.
+func (p *Packet) Flatten() []Record {
+	var records []Record
+	p.walk(make([]int, 0, 8), func(path []int, p *Packet) bool {
+		records = append(records, Record{
+			Path:  append([]int{}, path...),
+			Tag:   p.Tag,
+			Class: p.ClassType,
+			Value: p.Value,
+		})
+		return true
+	})
+	return records
+}
@@ -0,0 +1,79 @@
+package ber
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	// SEQUENCE { INTEGER 1, [0] { OCTET STRING "a", BOOLEAN TRUE }, NULL }
+	tagged := Encode(ClassContext, TypeConstructed, 0, nil, "tagged")
+	tagged.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "a", ""))
+	tagged.AppendChild(NewBoolean(ClassUniversal, TypePrimitive, TagBoolean, true, ""))
+	seq := NewSequence("")
+	seq.AppendChild(Integer(1))
+	seq.AppendChild(tagged)
+	seq.AppendChild(Encode(ClassUniversal, TypePrimitive, TagNULL, nil, ""))
+
+	decoded, err := DecodePacketErr(seq.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Record{
+		{Path: []int{}, Tag: TagSequence, Class: ClassUniversal},
+		{Path: []int{0}, Tag: TagInteger, Class: ClassUniversal, Value: int64(1)},
+		{Path: []int{1}, Tag: 0, Class: ClassContext},
+		{Path: []int{1, 0}, Tag: TagOctetString, Class: ClassUniversal, Value: "a"},
+		{Path: []int{1, 1}, Tag: TagBoolean, Class: ClassUniversal, Value: true},
+		{Path: []int{2}, Tag: TagNULL, Class: ClassUniversal},
+	}
+	if records := decoded.Flatten(); !reflect.DeepEqual(expected, records) {
+		t.Errorf("expected %v, got %v", expected, records)
+	}
+
+	var visited []int
+	decoded.Walk(func(depth int, p *Packet) bool {
+		visited = append(visited, depth)
+		return p.ClassType != ClassContext
+	})
+	if expected := []int{0, 1, 1, 1}; !reflect.DeepEqual(expected, visited) {
+		t.Errorf("expected children of [0] to be skipped, visited depths %v", visited)
+	}
+}
+
+func TestFindChildrenByTag(t *testing.T) {
+	// SEQUENCE { INTEGER 1, SEQUENCE { INTEGER 2, OCTET STRING, SEQUENCE { INTEGER 3 } }, [2] 4 }
+	deepest := NewSequence("")
+	deepest.AppendChild(Integer(3))
+	inner := NewSequence("")
+	inner.AppendChild(Integer(2))
+	inner.AppendChild(NewString(ClassUniversal, TypePrimitive, TagOctetString, "x", ""))
+	inner.AppendChild(deepest)
+	outer := NewSequence("")
+	outer.AppendChild(Integer(1))
+	outer.AppendChild(inner)
+	outer.AppendChild(NewInteger(ClassContext, TypePrimitive, TagInteger, 4, ""))
+
+	decoded, err := DecodePacketErr(outer.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var values []int64
+	for _, p := range decoded.FindChildrenByClassAndTag(ClassUniversal, TagInteger) {
+		values = append(values, p.Value.(int64))
+	}
+	if expected := []int64{1, 2, 3}; !reflect.DeepEqual(expected, values) {
+		t.Errorf("expected integers %v, got %v", expected, values)
+	}
+	if found := decoded.FindChildrenByClassAndTag(ClassContext, 2); len(found) != 1 || found[0].Data.Bytes()[0] != 4 {
+		t.Errorf("expected only the [2] packet, got %d packets", len(found))
+	}
+	if n := len(decoded.FindChildrenByTag(TagInteger)); n != 4 {
+		t.Errorf("expected 4 packets tagged 2 of any class, got %d", n)
+	}
+	if n := len(decoded.FindChildrenByTag(TagSequence)); n != 2 {
+		t.Errorf("expected the 2 nested sequences without the root, got %d", n)
+	}
+}